@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	githubactions "github.com/sethvargo/go-githubactions"
+)
+
+// Default readability thresholds
+const (
+	defaultMinReadingEase    = 30
+	defaultMaxSentenceWords  = 35
+	maxReportedSentenceWords = 12
+)
+
+// ReadabilityConfig sets the thresholds of the readability check in lint.yaml
+type ReadabilityConfig struct {
+	// Lowest acceptable Flesch reading ease score
+	MinScore *float64 `yaml:"min_score"`
+	// Sentences with more words are reported
+	MaxSentenceWords int `yaml:"max_sentence_words"`
+	// warning (the default), error or off
+	Severity string `yaml:"severity"`
+}
+
+// A LongSentence is a sentence with more words than the configured maximum
+type LongSentence struct {
+	Line  int
+	Words int
+	Text  string
+}
+
+// Readability is the Flesch reading ease of an article's prose and its overly long sentences
+type Readability struct {
+	Score         float64
+	Words         int
+	Sentences     int
+	LongSentences []LongSentence
+}
+
+var (
+	vowelGroupPattern = regexp.MustCompile(`[aeiouy]+`)
+	listItemPattern   = regexp.MustCompile(`^[ \t]*([-*+]|\d+\.)[ \t]+`)
+	sentenceSplitter  = regexp.MustCompile(`[.!?]+["')\]]*\s+`)
+)
+
+// Estimate the syllables of a word from its vowel groups, ignoring a silent final e
+func countSyllables(word string) int {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+	if word == "" {
+		return 0
+	}
+	syllables := len(vowelGroupPattern.FindAllString(word, -1))
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && syllables > 1 {
+		syllables--
+	}
+	return max(1, syllables)
+}
+
+// Flesch reading ease: 206.835 - 1.015 × words per sentence - 84.6 × syllables per word
+func fleschReadingEase(words int, sentences int, syllables int) float64 {
+	if words == 0 || sentences == 0 {
+		return 0
+	}
+	return 206.835 - 1.015*float64(words)/float64(sentences) - 84.6*float64(syllables)/float64(words)
+}
+
+// Split the content's prose into paragraphs with the line they start on. Headings, code
+// blocks and blank lines end a paragraph, and every list item starts a new one.
+func proseParagraphs(content string) ([]string, []int) {
+	var paragraphs []string
+	var starts []int
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inCode[i] || trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "<") {
+			flush()
+			continue
+		}
+		if listItemPattern.MatchString(line) {
+			flush()
+		}
+		if len(current) == 0 {
+			starts = append(starts, i+1)
+		}
+		current = append(current, line)
+	}
+	flush()
+	return paragraphs, starts
+}
+
+// Measure the readability of the content's prose, reporting sentences longer than maxWords
+func measureReadability(content string, maxWords int) Readability {
+	var readability Readability
+	var syllables int
+	paragraphs, starts := proseParagraphs(content)
+	for i, paragraph := range paragraphs {
+		text := stripMarkdown(paragraph)
+		offset := 0
+		for _, sentence := range splitSentences(text) {
+			index := offset + strings.Index(text[offset:], sentence)
+			line := starts[i] + strings.Count(text[:index], "\n")
+			offset = index + len(sentence)
+			words := strings.Fields(sentence)
+			if len(words) == 0 {
+				continue
+			}
+			readability.Sentences++
+			readability.Words += len(words)
+			for _, word := range words {
+				syllables += countSyllables(word)
+			}
+			if len(words) > maxWords {
+				preview := strings.Join(words[:min(len(words), maxReportedSentenceWords)], " ")
+				if len(words) > maxReportedSentenceWords {
+					preview += " …"
+				}
+				readability.LongSentences = append(readability.LongSentences, LongSentence{Line: line, Words: len(words), Text: preview})
+			}
+		}
+	}
+	readability.Score = fleschReadingEase(readability.Words, readability.Sentences, syllables)
+	return readability
+}
+
+// Split text into sentences at full stops, question and exclamation marks
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, end := range sentenceSplitter.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, strings.TrimSpace(text[start:end[1]]))
+		start = end[1]
+	}
+	if rest := strings.TrimSpace(text[start:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+// Check the readability against the configured thresholds
+func (config ReadabilityConfig) check(readability Readability) []Diagnostic {
+	severity := config.Severity
+	if severity == "" {
+		severity = severityWarning
+	}
+	if severity == severityOff {
+		return nil
+	}
+	minScore := float64(defaultMinReadingEase)
+	if config.MinScore != nil {
+		minScore = *config.MinScore
+	}
+	var diagnostics []Diagnostic
+	if readability.Sentences > 0 && readability.Score < minScore {
+		diagnostics = append(diagnostics, Diagnostic{Severity: severity,
+			Message: fmt.Sprintf("readability: Flesch reading ease is %.1f, the minimum is %.0f", readability.Score, minScore)})
+	}
+	for _, sentence := range readability.LongSentences {
+		diagnostics = append(diagnostics, Diagnostic{Severity: severity, Line: sentence.Line,
+			Message: fmt.Sprintf("readability: Sentence has %d words: %v", sentence.Words, sentence.Text)})
+	}
+	return diagnostics
+}
+
+func (config ReadabilityConfig) maxSentenceWords() int {
+	if config.MaxSentenceWords > 0 {
+		return config.MaxSentenceWords
+	}
+	return defaultMaxSentenceWords
+}
+
+// Alt text of the images referenced in the content, by image name without extension
+func imageAltTexts(content string) map[string]string {
+	alts := map[string]string{}
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] {
+			continue
+		}
+		for _, image := range imageReferencePattern.FindAllStringSubmatch(line, -1) {
+			name := filepath.Base(image[2])
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if alt := strings.TrimSpace(image[1]); alt != "" && alts[name] == "" {
+				alts[name] = alt
+			}
+		}
+	}
+	return alts
+}
+
+// Describe the reading ease score in words
+func readingEaseLevel(score float64) string {
+	switch {
+	case score >= 80:
+		return "easy"
+	case score >= 60:
+		return "plain English"
+	case score >= 50:
+		return "fairly difficult"
+	case score >= 30:
+		return "difficult"
+	}
+	return "very difficult"
+}
+
+// Add an article's readability score to the job summary. Long sentences and a low score are
+// listed with the article's other diagnostics.
+func summarizeReadability(action *githubactions.Action, title string, readability Readability) {
+	if readability.Sentences == 0 {
+		return
+	}
+	action.AddStepSummary(fmt.Sprintf("### Readability of %v\n\nFlesch reading ease **%.1f** (%v), %d words in %d sentences.\n",
+		title, readability.Score, readingEaseLevel(readability.Score), readability.Words, readability.Sentences))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCountSyllables(t *testing.T) {
+	tests := map[string]int{"the": 1, "table": 2, "readability": 5, "make": 1, "beautiful": 3, "Go!": 1, "42": 0}
+	for word, want := range tests {
+		if got := countSyllables(word); got != want {
+			t.Fatalf(`countSyllables(%q) = %d, but need %d`, word, got, want)
+		}
+	}
+}
+
+func TestMeasureReadability(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	content := strings.Join([]string{
+		"# Heading is skipped",
+		"The cat sat on the mat. The dog ran.",
+		"",
+		"```",
+		"code is skipped entirely",
+		"```",
+		"- " + long,
+		"- Short item",
+	}, "\n")
+
+	readability := measureReadability(content, 35)
+	if readability.Sentences != 4 || readability.Words != 6+3+40+2 {
+		t.Fatalf(`measureReadability = %+v, but need 4 sentences of 51 words`, readability)
+	}
+	want := []LongSentence{{Line: 7, Words: 40, Text: "word word word word word word word word word word word word …"}}
+	if !reflect.DeepEqual(readability.LongSentences, want) {
+		t.Fatalf(`measureReadability long sentences = %+v, but need %+v`, readability.LongSentences, want)
+	}
+	// 51 words, 4 sentences and 52 syllables
+	if wantScore := fleschReadingEase(51, 4, 52); math.Abs(readability.Score-wantScore) > 0.001 {
+		t.Fatalf(`measureReadability score = %v, but need %v`, readability.Score, wantScore)
+	}
+}
+
+func TestReadabilityCheck(t *testing.T) {
+	readability := Readability{Score: 25, Sentences: 3, LongSentences: []LongSentence{{Line: 4, Words: 50, Text: "A long one …"}}}
+	want := []Diagnostic{
+		{Severity: severityWarning, Message: "readability: Flesch reading ease is 25.0, the minimum is 30"},
+		{Severity: severityWarning, Line: 4, Message: "readability: Sentence has 50 words: A long one …"},
+	}
+	if got := (ReadabilityConfig{}).check(readability); !reflect.DeepEqual(got, want) {
+		t.Fatalf(`check = %v, but need %v`, got, want)
+	}
+	minScore := 20.0
+	if got := (ReadabilityConfig{MinScore: &minScore, Severity: severityError}).check(readability); len(got) != 1 || got[0].Severity != severityError {
+		t.Fatalf(`check = %v, but need one error for the long sentence`, got)
+	}
+	if got := (ReadabilityConfig{Severity: severityOff}).check(readability); got != nil {
+		t.Fatalf(`check = %v, but need nothing when off`, got)
+	}
+}
+
+func TestCreateArticlePayloadAltText(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "gophers")
+	writeTestFile(t, filepath.Join(folder, "gophers.md"), "A gopher:\n\n![A blue gopher](photos/gopher.png)\n\n![](photos/other.png)")
+	writeTestFile(t, filepath.Join(folder, "photos", "gopher.png"), "\x89PNG\r\n\x1a\n")
+	writeTestFile(t, filepath.Join(folder, "photos", "other.png"), "\x89PNG\r\n\x1a\n")
+
+	name, articleFile, photos, err := parseArticle(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The third image is the generated cover
+	if len(article.Images) != 3 || article.Images[0].Alt != "A blue gopher" || article.Images[1].Alt != "" || article.Images[2].Filename != "cover" {
+		t.Fatalf(`createArticlePayload images = %+v, but need the alt text of gopher`, article.Images)
+	}
+	want := Diagnostic{Severity: severityWarning, File: articleFile, Line: 5, Message: "image-alt-text: Image photos/other.png has no alt text"}
+	if !reflect.DeepEqual(article.Diagnostics, []Diagnostic{want}) {
+		t.Fatalf(`createArticlePayload diagnostics = %v, but need %v`, article.Diagnostics, want)
+	}
+
+	// Alt text in captions.yaml describes the image as well
+	writeTestFile(t, filepath.Join(photos, captionsFile), "other.png: {alt: Another gopher}\n")
+	article, err = createArticlePayload(name, articleFile, photos)
+	if err != nil || len(article.Diagnostics) != 0 {
+		t.Fatalf(`createArticlePayload diagnostics = %v, %v, but need none with the alt text in %v`, article.Diagnostics, err, captionsFile)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// An Attachment is a non-image file (slides, datasets, source archives) that is
+// uploaded through its own endpoint and linked to from the article content.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"`
+	Data        string `json:"data"`
+	Path        string `json:"-"`
+	Filepath    string `json:"-"`
+}
+
+type attachmentResponse struct {
+	URL      string `json:"url"`
+	Checksum string `json:"checksum"`
+}
+
+// Collect the files in the `attachments/` folder and any attachments listed in the front matter.
+// Front matter entries are relative to the article folder and may not point outside of it.
+func collectAttachments(articleFolder string, frontMatter FrontMatter) ([]Attachment, error) {
+	var paths []string
+	attachmentFolder := filepath.Join(articleFolder, "attachments")
+	files, err := os.ReadDir(attachmentFolder)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Error reading attachments folder: %v", err)
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		paths = append(paths, filepath.Join("attachments", file.Name()))
+	}
+	for _, entry := range frontMatter.Attachments {
+		path := filepath.Clean(entry)
+		if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("Attachment %v must be inside the article folder", entry)
+		}
+		paths = append(paths, path)
+	}
+
+	// Follow symlinks so a link inside the article folder cannot point outside it. The folder
+	// is made absolute first as symlinks with absolute targets resolve to absolute paths.
+	folder, err := filepath.Abs(articleFolder)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading article folder: %v", err)
+	}
+	if folder, err = filepath.EvalSymlinks(folder); err != nil {
+		return nil, fmt.Errorf("Error reading article folder: %v", err)
+	}
+	var attachments []Attachment
+	seen := map[string]bool{}
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		resolved, err := filepath.EvalSymlinks(filepath.Join(folder, path))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading attachment %v: %v", path, err)
+		}
+		if !insideFolder(folder, resolved) {
+			return nil, fmt.Errorf("Attachment %v must be inside the article folder", path)
+		}
+		attachment, err := createAttachmentPayload(articleFolder, path)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func createAttachmentPayload(articleFolder string, path string) (Attachment, error) {
+	attachmentFile := filepath.Join(articleFolder, path)
+	raw_data, err := os.ReadFile(attachmentFile)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("Error reading attachment %v: %v", attachmentFile, err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(attachmentFile))
+	if contentType == "" {
+		contentType = http.DetectContentType(raw_data)
+	}
+	checksum := sha256.Sum256(raw_data)
+	logger.Debug(fmt.Sprintf("Created attachment payload for %v with content type %v", attachmentFile, contentType))
+	return Attachment{
+		Filename:    filepath.Base(attachmentFile),
+		ContentType: contentType,
+		Size:        int64(len(raw_data)),
+		Checksum:    hex.EncodeToString(checksum[:]),
+		Data:        b64.StdEncoding.EncodeToString(raw_data),
+		Path:        filepath.ToSlash(path),
+		Filepath:    attachmentFile,
+	}, nil
+}
+
+// Find an attachment the server already has with the same checksum, so uploading an article
+// again, or after a failed upload, does not store its attachments twice
+func findUploadedAttachment(endpoint string, checksum string) (string, bool) {
+	var existing []attachmentResponse
+	if err := sendAuthorizedJSON(http.MethodGet, endpoint+"?checksum="+checksum, nil, &existing); err != nil {
+		logger.Debug("Could not look up existing attachment", "checksum", checksum, "error", err)
+		return "", false
+	}
+	for _, attachment := range existing {
+		if attachment.Checksum == checksum && attachment.URL != "" {
+			return attachment.URL, true
+		}
+	}
+	return "", false
+}
+
+// Upload each attachment to the ATTACHMENT_ENDPOINT, returning the hosted url for each attachment
+// path. Attachments the server already has are not uploaded again.
+func uploadAttachments(attachments []Attachment) (map[string]string, error) {
+	url, err := apiURL("ATTACHMENT_ENDPOINT")
+	if err != nil {
+		return nil, err
+	}
+	urls := map[string]string{}
+	for _, attachment := range attachments {
+		if existing, ok := findUploadedAttachment(url, attachment.Checksum); ok {
+			logger.Info("Attachment is already uploaded", "attachment", attachment.Path, "url", existing)
+			urls[attachment.Path] = existing
+			continue
+		}
+		var response attachmentResponse
+		if err := sendAuthorizedJSON(http.MethodPost, url, attachment, &response); err != nil {
+			return nil, fmt.Errorf("Error uploading attachment %v: %v", attachment.Path, err)
+		}
+		if response.URL == "" {
+			return nil, fmt.Errorf("Server did not return a url for attachment %v", attachment.Path)
+		}
+		logger.Info("Uploaded attachment", "attachment", attachment.Path, "url", response.URL)
+		urls[attachment.Path] = response.URL
+	}
+	return urls, nil
+}
+
+var markdownLinkPattern = regexp.MustCompile(`(!?\[[^\]]*\]\()([^)\s]+)((?:\s+"[^"]*")?\))`)
+
+// Rewrite markdown links that point at uploaded attachments to their hosted urls, outside of
+// code blocks and inline code
+func rewriteAttachmentLinks(content string, urls map[string]string) string {
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] || !strings.Contains(line, "](") {
+			continue
+		}
+		masked, spans := maskInlineCode(line)
+		masked = markdownLinkPattern.ReplaceAllStringFunc(masked, func(link string) string {
+			parts := markdownLinkPattern.FindStringSubmatch(link)
+			target := filepath.ToSlash(filepath.Clean(parts[2]))
+			url, ok := urls[target]
+			if !ok {
+				return link
+			}
+			return parts[1] + url + parts[3]
+		})
+		lines[i] = unmaskInlineCode(masked, spans)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Point the api helpers at a test server
+func useTestServer(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	t.Setenv("ENV", "DEV")
+	t.Setenv("BASE_DOMAIN", strings.TrimPrefix(server.URL, "http://"))
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	content := "---\nattachments:\n  - data/results.csv\n---\n# Title\nBody"
+	frontMatter, body, err := parseFrontMatter(content)
+	if err != nil {
+		t.Fatalf(`parseFrontMatter returned unexpected error: %v`, err)
+	}
+	if body != "# Title\nBody" {
+		t.Fatalf(`parseFrontMatter body = %q, but need %q`, body, "# Title\nBody")
+	}
+	if len(frontMatter.Attachments) != 1 || frontMatter.Attachments[0] != "data/results.csv" {
+		t.Fatalf(`parseFrontMatter attachments = %v, but need [data/results.csv]`, frontMatter.Attachments)
+	}
+
+	if _, body, err := parseFrontMatter("No front matter"); err != nil || body != "No front matter" {
+		t.Fatalf(`parseFrontMatter("No front matter") = %q, %v`, body, err)
+	}
+	if _, _, err := parseFrontMatter("---\ntitle: unterminated\n"); err == nil {
+		t.Fatalf(`parseFrontMatter expected an error for unterminated front matter`)
+	}
+}
+
+func TestCollectAttachments(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "attachments", "slides.pdf"), "%PDF-1.7")
+	writeTestFile(t, filepath.Join(articleFolder, "data", "results.csv"), "a,b\n1,2\n")
+
+	attachments, err := collectAttachments(articleFolder, FrontMatter{Attachments: []string{"data/results.csv", "attachments/slides.pdf"}})
+	if err != nil {
+		t.Fatalf(`collectAttachments returned unexpected error: %v`, err)
+	}
+	if len(attachments) != 2 {
+		t.Fatalf(`collectAttachments returned %d attachments, but need 2: %v`, len(attachments), attachments)
+	}
+	pdf := attachments[0]
+	if pdf.Path != "attachments/slides.pdf" || pdf.ContentType != "application/pdf" || pdf.Size != 8 {
+		t.Fatalf(`unexpected pdf attachment: %+v`, pdf)
+	}
+	if pdf.Checksum != "86edbaa24831badfa0a8b04bb410141e2ee4182b6d0014493fe262a7a331c20b" {
+		t.Fatalf(`unexpected pdf checksum: %v`, pdf.Checksum)
+	}
+	if attachments[1].Path != "data/results.csv" || !strings.HasPrefix(attachments[1].ContentType, "text/csv") {
+		t.Fatalf(`unexpected csv attachment: %+v`, attachments[1])
+	}
+
+	if _, err := collectAttachments(articleFolder, FrontMatter{Attachments: []string{"../secrets.txt"}}); err == nil {
+		t.Fatalf(`collectAttachments expected an error for an attachment outside the article folder`)
+	}
+
+	outside := filepath.Join(t.TempDir(), "secrets.txt")
+	writeTestFile(t, outside, "secret")
+	if err := os.Symlink(outside, filepath.Join(articleFolder, "attachments", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collectAttachments(articleFolder, FrontMatter{}); err == nil {
+		t.Fatalf(`collectAttachments expected an error for a symlink pointing outside the article folder`)
+	}
+}
+
+func TestCollectAttachmentsAbsoluteSymlink(t *testing.T) {
+	root := t.TempDir()
+	articleFolder := filepath.Join(root, "article")
+	writeTestFile(t, filepath.Join(articleFolder, "data", "results.csv"), "a,b\n1,2\n")
+	if err := os.Symlink(filepath.Join(articleFolder, "data", "results.csv"), filepath.Join(articleFolder, "data", "latest.csv")); err != nil {
+		t.Fatal(err)
+	}
+	// The article folder is given relative to the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	attachments, err := collectAttachments("article", FrontMatter{Attachments: []string{"data/latest.csv"}})
+	if err != nil {
+		t.Fatalf(`collectAttachments returned unexpected error for a symlink inside the article folder: %v`, err)
+	}
+	if len(attachments) != 1 || attachments[0].Path != "data/latest.csv" || attachments[0].Size != 8 {
+		t.Fatalf(`collectAttachments = %+v, but need data/latest.csv`, attachments)
+	}
+}
+
+func TestUploadAttachmentsAndRewriteLinks(t *testing.T) {
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			// The server already has the data set, and ignores the checksum it is asked for
+			json.NewEncoder(w).Encode([]attachmentResponse{{URL: "https://cdn.example.com/old.csv", Checksum: "other"}, {URL: "https://cdn.example.com/results.csv", Checksum: "def"}})
+			return
+		}
+		var attachment Attachment
+		if err := json.NewDecoder(r.Body).Decode(&attachment); err != nil {
+			t.Errorf("Error decoding attachment: %v", err)
+		}
+		posted = append(posted, attachment.Filename)
+		json.NewEncoder(w).Encode(attachmentResponse{URL: "https://cdn.example.com/" + attachment.Filename})
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("ATTACHMENT_ENDPOINT", "api/attachments/")
+
+	urls, err := uploadAttachments([]Attachment{{Filename: "slides.pdf", Path: "attachments/slides.pdf", Checksum: "abc"}, {Filename: "results.csv", Path: "data/results.csv", Checksum: "def"}})
+	if err != nil {
+		t.Fatalf(`uploadAttachments returned unexpected error: %v`, err)
+	}
+	if len(posted) != 1 || urls["data/results.csv"] != "https://cdn.example.com/results.csv" {
+		t.Fatalf(`uploadAttachments posted %v and returned %v, but need the existing data set reused`, posted, urls)
+	}
+	content := "See [the slides](./attachments/slides.pdf \"Slides\") and [other](notes.md)\nWrite `[x](data/results.csv)` to link it\n```\n[x](data/results.csv)\n```"
+	want := "See [the slides](https://cdn.example.com/slides.pdf \"Slides\") and [other](notes.md)\nWrite `[x](data/results.csv)` to link it\n```\n[x](data/results.csv)\n```"
+	if got := rewriteAttachmentLinks(content, urls); got != want {
+		t.Fatalf(`rewriteAttachmentLinks = %q, but need %q`, got, want)
+	}
+}
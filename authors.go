@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const authorRegistryFile = "authors.yaml"
+
+// An Author maps the git identities of a writer to their user on the site
+type Author struct {
+	ID     *int     `yaml:"id" json:"id,omitempty"`
+	Name   string   `yaml:"name" json:"name"`
+	Bio    string   `yaml:"bio" json:"bio,omitempty"`
+	GitHub string   `yaml:"github" json:"github,omitempty"`
+	Emails []string `yaml:"emails" json:"-"`
+}
+
+// AuthorRegistry is read from authors.yaml at the repository root, keyed by a short handle
+type AuthorRegistry map[string]Author
+
+// GitHub noreply addresses look like 12345+handle@users.noreply.github.com
+var noreplyEmailPattern = regexp.MustCompile(`^(?:\d+\+)?([^@]+)@users\.noreply\.github\.com$`)
+
+// Load the author registry, returning nil when the repository does not have one
+func loadAuthorRegistry(root string) (AuthorRegistry, error) {
+	data, err := os.ReadFile(filepath.Join(root, authorRegistryFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", authorRegistryFile, err)
+	}
+	var registry AuthorRegistry
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("Error parsing %v: %v", authorRegistryFile, err)
+	}
+	return registry, nil
+}
+
+// Find an author by registry handle, GitHub handle or email
+func (registry AuthorRegistry) lookup(identity string) (Author, bool) {
+	identity = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(identity), "@"))
+	if match := noreplyEmailPattern.FindStringSubmatch(identity); match != nil {
+		identity = match[1]
+	}
+	for _, handle := range slices.Sorted(maps.Keys(registry)) {
+		author := registry[handle]
+		if strings.ToLower(handle) == identity || strings.ToLower(author.GitHub) == identity {
+			return author, true
+		}
+		for _, email := range author.Emails {
+			if strings.ToLower(email) == identity {
+				return author, true
+			}
+		}
+	}
+	return Author{}, false
+}
+
+// Resolve the author of an article from its `author` front matter, or from the
+// most recent author in its git history when the front matter does not name one.
+func (registry AuthorRegistry) resolve(frontMatterAuthor string, gitAuthor *GitAuthor) (Author, error) {
+	if frontMatterAuthor != "" {
+		author, ok := registry.lookup(frontMatterAuthor)
+		if !ok {
+			return Author{}, fmt.Errorf("Author %q is not in %v", frontMatterAuthor, authorRegistryFile)
+		}
+		return author, nil
+	}
+	if gitAuthor == nil {
+		return Author{}, fmt.Errorf("Article has no `author` front matter or git history to find its author from, set `author` or check out the repository with fetch-depth: 0")
+	}
+	author, ok := registry.lookup(gitAuthor.Email)
+	if !ok {
+		return Author{}, fmt.Errorf("Git author %v <%v> is not in %v", gitAuthor.Name, gitAuthor.Email, authorRegistryFile)
+	}
+	return author, nil
+}
+
+// Resolve the server id of an article's author. Articles in repositories without an
+// author registry are sent without an author.
+func resolveAuthorID(articleFile string, frontMatter FrontMatter, gitMetadata GitMetadata) (*int, error) {
+	registry, err := loadAuthorRegistry(repoRoot(filepath.Dir(articleFile)))
+	if err != nil {
+		return nil, err
+	}
+	if registry == nil {
+		if frontMatter.Author != "" {
+			logger.Warn("Article names an author but there is no author registry", "author", frontMatter.Author)
+		}
+		return nil, nil
+	}
+	author, err := registry.resolve(frontMatter.Author, gitMetadata.LatestAuthor)
+	if err != nil {
+		return nil, err
+	}
+	if author.ID == nil {
+		return nil, fmt.Errorf("Author %v does not have a server id in %v, run sync-authors first", author.Name, authorRegistryFile)
+	}
+	return author.ID, nil
+}
+
+// Create or update every author in the registry at root through the AUTHOR_ENDPOINT, writing
+// the ids of created authors back to the registry
+func syncAuthors(root string, registry AuthorRegistry) error {
+	endpoint, err := apiURL("AUTHOR_ENDPOINT")
+	if err != nil {
+		return err
+	}
+	created := map[string]int{}
+	for _, handle := range slices.Sorted(maps.Keys(registry)) {
+		author := registry[handle]
+		method, url := http.MethodPost, endpoint
+		if author.ID != nil {
+			method, url = http.MethodPatch, endpoint+fmt.Sprintf("%d/", *author.ID)
+		}
+		var synced Author
+		if err := sendAuthorizedJSON(method, url, author, &synced); err != nil {
+			return fmt.Errorf("Error syncing author %v: %v", handle, err)
+		}
+		logger.Info("Synced author", "author", handle, "id", synced.ID)
+		if author.ID == nil && synced.ID != nil {
+			created[handle] = *synced.ID
+			author.ID = synced.ID
+			registry[handle] = author
+		}
+	}
+	if len(created) == 0 {
+		return nil
+	}
+	if err := recordAuthorIDs(root, created); err != nil {
+		return err
+	}
+	logger.Info("Recorded the ids of created authors, commit the updated registry", "file", authorRegistryFile, "authors", created)
+	return nil
+}
+
+// Set the ids of authors in the registry file, keeping its order and comments
+func recordAuthorIDs(root string, ids map[string]int) error {
+	path := filepath.Join(root, authorRegistryFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Error reading %v: %v", authorRegistryFile, err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("Error parsing %v: %v", authorRegistryFile, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("Error parsing %v: expected a mapping of authors", authorRegistryFile)
+	}
+	authors := document.Content[0].Content
+	for i := 0; i+1 < len(authors); i += 2 {
+		id, ok := ids[authors[i].Value]
+		if !ok || authors[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(id)}
+		fields := authors[i+1].Content
+		found := false
+		for j := 0; j+1 < len(fields); j += 2 {
+			if fields[j].Value == "id" {
+				fields[j+1], found = value, true
+			}
+		}
+		if !found {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "id"}
+			authors[i+1].Content = append([]*yaml.Node{key, value}, fields...)
+		}
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return fmt.Errorf("Error writing %v: %v", authorRegistryFile, err)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Error writing %v: %v", authorRegistryFile, err)
+	}
+	return nil
+}
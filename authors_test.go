@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const testAuthorRegistry = `
+ada:
+  id: 4
+  name: Ada Lovelace
+  bio: First programmer
+  github: adal
+  emails: [ada@example.com]
+grace:
+  name: Grace Hopper
+  emails: [grace@example.com]
+`
+
+func TestAuthorRegistryResolve(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "authors.yaml"), testAuthorRegistry)
+	registry, err := loadAuthorRegistry(root)
+	if err != nil {
+		t.Fatalf(`loadAuthorRegistry returned unexpected error: %v`, err)
+	}
+
+	tests := []struct {
+		frontMatter string
+		gitAuthor   *GitAuthor
+		want        string
+	}{
+		{"ada", nil, "Ada Lovelace"},
+		{"@adal", nil, "Ada Lovelace"},
+		{"GRACE@example.com", nil, "Grace Hopper"},
+		{"grace", &GitAuthor{Name: "Ada", Email: "ada@example.com"}, "Grace Hopper"},
+		{"", &GitAuthor{Name: "Grace", Email: "grace@example.com"}, "Grace Hopper"},
+		{"", &GitAuthor{Name: "Ada", Email: "1234+adal@users.noreply.github.com"}, "Ada Lovelace"},
+	}
+	for _, test := range tests {
+		author, err := registry.resolve(test.frontMatter, test.gitAuthor)
+		if err != nil || author.Name != test.want {
+			t.Errorf(`resolve(%q, %v) = %v, %v, but need %v`, test.frontMatter, test.gitAuthor, author.Name, err, test.want)
+		}
+	}
+
+	if _, err := registry.resolve("linus", nil); err == nil {
+		t.Errorf(`resolve("linus") expected an error for an unknown author`)
+	}
+	if _, err := registry.resolve("", &GitAuthor{Name: "Linus", Email: "linus@example.com"}); err == nil {
+		t.Errorf(`resolve expected an error for an unknown git author`)
+	}
+}
+
+func TestCreateArticlePayloadAuthor(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	writeTestFile(t, filepath.Join(root, "authors.yaml"), testAuthorRegistry)
+	articleFile := filepath.Join(root, "intro", "intro.md")
+
+	writeTestFile(t, articleFile, "---\nauthor: ada\n---\nHello")
+	article, err := createArticlePayload("intro", articleFile, "")
+	if err != nil || article.AuthorID == nil || *article.AuthorID != 4 {
+		t.Fatalf(`createArticlePayload = %+v, %v, but need author 4`, article, err)
+	}
+
+	// Grace has not been synced to the server so has no id yet
+	writeTestFile(t, articleFile, "---\nauthor: grace\n---\nHello")
+	if _, err := createArticlePayload("intro", articleFile, ""); err == nil {
+		t.Fatalf(`createArticlePayload expected an error for an author without a server id`)
+	}
+
+	// Without front matter or git history the author cannot be resolved
+	writeTestFile(t, articleFile, "Hello")
+	if _, err := createArticlePayload("intro", articleFile, ""); err == nil {
+		t.Fatalf(`createArticlePayload expected an error for an article without an author or git history`)
+	}
+}
+
+func TestSyncAuthors(t *testing.T) {
+	requests := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var author Author
+		json.NewDecoder(r.Body).Decode(&author)
+		requests[author.Name] = r.Method + " " + r.URL.Path
+		id := 7
+		author.ID = &id
+		json.NewEncoder(w).Encode(author)
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("AUTHOR_ENDPOINT", "api/authors/")
+
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "authors.yaml"), testAuthorRegistry)
+	registry, err := loadAuthorRegistry(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syncAuthors(root, registry); err != nil {
+		t.Fatalf(`syncAuthors returned unexpected error: %v`, err)
+	}
+	if requests["Ada Lovelace"] != "PATCH /api/authors/4/" || requests["Grace Hopper"] != "POST /api/authors/" {
+		t.Fatalf(`syncAuthors sent unexpected requests: %v`, requests)
+	}
+
+	// The new id is written back and the existing one is kept
+	recorded, err := loadAuthorRegistry(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grace := recorded["grace"]; grace.ID == nil || *grace.ID != 7 || grace.Name != "Grace Hopper" || *recorded["ada"].ID != 4 {
+		t.Fatalf(`syncAuthors recorded registry = %+v`, recorded)
+	}
+}
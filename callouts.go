@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Kinds of callout and their default titles
+var calloutTitles = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+}
+
+// Built in callout templates for CALLOUT_TEMPLATE. The body is markdown, so the html
+// template keeps it apart from the tags with blank lines.
+var calloutTemplates = map[string]string{
+	"html":   "<div class=\"callout callout-{{.Type}}\">\n<p class=\"callout-title\">{{html .Title}}</p>\n\n{{.Body}}\n\n</div>",
+	"mkdocs": "!!! {{.Type}} \"{{.Title}}\"\n\n{{indent 4 .Body}}",
+	"hugo":   "{{`{{<`}} callout {{.Type}} title={{printf \"%q\" .Title}} {{`>}}`}}\n{{.Body}}\n{{`{{<`}} /callout {{`>}}`}}",
+}
+
+// > [!NOTE] alert markers, optionally followed by a title
+var alertPattern = regexp.MustCompile(`(?i)^[ \t]{0,3}>[ \t]*\[!(note|tip|important|warning|caution)\][ \t]*(.*)$`)
+
+var blockquotePattern = regexp.MustCompile(`^[ \t]{0,3}>[ \t]?`)
+
+// The callout template named by CALLOUT_TEMPLATE: html (the default), mkdocs, hugo, or
+// a text/template file relative to the repository root.
+func loadCalloutTemplate(root string) (*template.Template, error) {
+	name := os.Getenv("CALLOUT_TEMPLATE")
+	if name == "" {
+		name = "html"
+	}
+	text, ok := calloutTemplates[name]
+	if !ok {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading callout template %v: %v", name, err)
+		}
+		text = string(data)
+	}
+	parsed, err := template.New("callout").Funcs(template.FuncMap{"indent": indentLines}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing callout template %v: %v", name, err)
+	}
+	return parsed, nil
+}
+
+// Indent every non-empty line of text by width spaces
+func indentLines(width int, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = strings.Repeat(" ", width) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render a callout box of the given kind with the configured template
+func renderCallout(root string, kind string, title string, body string) (string, error) {
+	kind = strings.ToLower(kind)
+	if _, ok := calloutTitles[kind]; !ok {
+		return "", fmt.Errorf("Unknown callout type %q, expected one of %v", kind, strings.Join(slices.Sorted(maps.Keys(calloutTitles)), ", "))
+	}
+	if title == "" {
+		title = calloutTitles[kind]
+	}
+	calloutTemplate, err := loadCalloutTemplate(root)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	err = calloutTemplate.Execute(&rendered, map[string]string{"Type": kind, "Title": title, "Body": body})
+	if err != nil {
+		return "", fmt.Errorf("Error rendering callout: %v", err)
+	}
+	return strings.TrimSpace(rendered.String()), nil
+}
+
+// Convert GitHub alerts (a blockquote starting with > [!NOTE], > [!WARNING], ...) into callouts,
+// returning the converted content with the line of the content each of its lines came from
+func convertAlerts(content string, root string) (string, []int, error) {
+	lines, inCode := markdownLines(content)
+	converted := &lineTracker{}
+	for i := 0; i < len(lines); i++ {
+		match := alertPattern.FindStringSubmatch(lines[i])
+		if inCode[i] || match == nil {
+			converted.writeLine(lines[i], i)
+			continue
+		}
+		marker := i
+		var body []string
+		for i+1 < len(lines) && !inCode[i+1] && blockquotePattern.MatchString(lines[i+1]) {
+			i++
+			body = append(body, blockquotePattern.ReplaceAllString(lines[i], ""))
+		}
+		text := strings.TrimSpace(strings.Join(body, "\n"))
+		callout, err := renderCallout(root, match[1], strings.TrimSpace(match[2]), text)
+		if err != nil {
+			return "", nil, err
+		}
+		// The body keeps its lines when the template writes it unchanged, and the rest of the
+		// callout comes from the alert marker
+		bodyStart := strings.Index(callout, text)
+		if text == "" || bodyStart == -1 {
+			converted.writeLine("", marker)
+			converted.write(callout, marker)
+			continue
+		}
+		firstBodyLine := marker + 1 + slices.IndexFunc(body, func(line string) bool { return strings.TrimSpace(line) != "" })
+		converted.writeLine("", marker)
+		converted.write(callout[:bodyStart], marker)
+		converted.writeLines(text, firstBodyLine)
+		converted.write(callout[bodyStart+len(text):], firstBodyLine+strings.Count(text, "\n"))
+	}
+	return converted.String(), converted.origins, nil
+}
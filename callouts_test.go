@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertAlerts(t *testing.T) {
+	content := strings.Join([]string{
+		"Intro",
+		"",
+		"> [!NOTE]",
+		"> Useful information.",
+		">",
+		"> More **detail**.",
+		"",
+		"> [!warning] Mind the gap",
+		"> Be careful.",
+		"",
+		"> A plain quote",
+		"```",
+		"> [!TIP]",
+		"```",
+	}, "\n")
+	want := strings.Join([]string{
+		"Intro",
+		"",
+		`<div class="callout callout-note">`,
+		`<p class="callout-title">Note</p>`,
+		"",
+		"Useful information.",
+		"",
+		"More **detail**.",
+		"",
+		"</div>",
+		"",
+		`<div class="callout callout-warning">`,
+		`<p class="callout-title">Mind the gap</p>`,
+		"",
+		"Be careful.",
+		"",
+		"</div>",
+		"",
+		"> A plain quote",
+		"```",
+		"> [!TIP]",
+		"```",
+	}, "\n")
+
+	got, origins, err := convertAlerts(content, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("convertAlerts =\n%v\nbut need\n%v", got, want)
+	}
+	// Callout bodies keep their lines and the rest of the callout comes from the alert marker
+	wantOrigins := []int{0, 1, 2, 2, 2, 3, 4, 5, 5, 5, 6, 7, 7, 7, 8, 8, 8, 9, 10, 11, 12, 13}
+	if !reflect.DeepEqual(origins, wantOrigins) {
+		t.Fatalf(`convertAlerts origins = %v, but need %v`, origins, wantOrigins)
+	}
+}
+
+func TestConvertAlertsTemplates(t *testing.T) {
+	content := "> [!TIP]\n> Line one\n> line two"
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "callout.tmpl"), `<aside data-type="{{.Type}}">{{.Title}}: {{.Body}}</aside>`)
+
+	tests := map[string]string{
+		"mkdocs":       "!!! tip \"Tip\"\n\n    Line one\n    line two",
+		"hugo":         "{{< callout tip title=\"Tip\" >}}\nLine one\nline two\n{{< /callout >}}",
+		"callout.tmpl": `<aside data-type="tip">Tip: Line one` + "\nline two</aside>",
+	}
+	for name, want := range tests {
+		t.Setenv("CALLOUT_TEMPLATE", name)
+		got, _, err := convertAlerts(content, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("convertAlerts with %v =\n%v\nbut need\n%v", name, got, want)
+		}
+	}
+
+	t.Setenv("CALLOUT_TEMPLATE", "missing.tmpl")
+	if _, _, err := convertAlerts(content, root); err == nil {
+		t.Fatalf("convertAlerts with a missing template should fail")
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	bibliographyFile  = "references.bib"
+	referencesHeading = "## References"
+)
+
+// Citation styles, chosen with the `citation_style` front matter or CITATION_STYLE
+const (
+	citationNumeric    = "numeric"
+	citationAuthorYear = "author-year"
+)
+
+// [@knuth1984] and [@knuth1984, p. 12; @lamport1994] citations. A following ( or [ is matched
+// so that [@handle](url) and [@handle][ref] links can be told apart from citations.
+var citationPattern = regexp.MustCompile(`\[(@[\w:.\-/]+(?:,[^;\]]*)?(?:;\s*@[\w:.\-/]+(?:,[^;\]]*)?)*)\][(\[]?`)
+
+var citationKeyPattern = regexp.MustCompile(`^@([\w:.\-/]+)(?:,\s*(.*))?$`)
+
+var bibNameSeparatorPattern = regexp.MustCompile(`\s+and\s+`)
+
+// A BibEntry is an entry of a BibTeX file, with field names in lower case
+type BibEntry struct {
+	Type   string
+	Key    string
+	Fields map[string]string
+}
+
+// Standard month macros
+var bibMonths = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April", "may": "May", "jun": "June",
+	"jul": "July", "aug": "August", "sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// LaTeX escapes commonly found in BibTeX values
+var latexReplacer = strings.NewReplacer(`\&`, "&", `\%`, "%", `\$`, "$", `\_`, "_", `\#`, "#", "--", "–", "~", " ")
+
+type bibParser struct {
+	data    string
+	pos     int
+	strings map[string]string
+}
+
+func (parser *bibParser) errorf(format string, args ...any) error {
+	line := strings.Count(parser.data[:min(parser.pos, len(parser.data))], "\n") + 1
+	return fmt.Errorf("line %d: %v", line, fmt.Sprintf(format, args...))
+}
+
+func (parser *bibParser) skipSpace() {
+	for parser.pos < len(parser.data) && unicode.IsSpace(rune(parser.data[parser.pos])) {
+		parser.pos++
+	}
+}
+
+func (parser *bibParser) identifier() string {
+	start := parser.pos
+	for parser.pos < len(parser.data) && !strings.ContainsRune(" \t\r\n{}(),=#\"", rune(parser.data[parser.pos])) {
+		parser.pos++
+	}
+	return parser.data[start:parser.pos]
+}
+
+// Read a {braced} or "quoted" string, returning its content without the delimiters
+func (parser *bibParser) delimited() (string, error) {
+	open := parser.data[parser.pos]
+	start := parser.pos
+	depth := 0
+	for ; parser.pos < len(parser.data); parser.pos++ {
+		switch c := parser.data[parser.pos]; {
+		case c == '\\':
+			parser.pos++
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if open == '{' && depth == 0 {
+				parser.pos++
+				return parser.data[start+1 : parser.pos-1], nil
+			}
+		case c == '"' && open == '"' && depth == 0 && parser.pos > start:
+			parser.pos++
+			return parser.data[start+1 : parser.pos-1], nil
+		}
+	}
+	parser.pos = start
+	return "", parser.errorf("unclosed %c", open)
+}
+
+// Read a field value: braced and quoted strings, numbers and macros joined with #
+func (parser *bibParser) value() (string, error) {
+	var value strings.Builder
+	for {
+		parser.skipSpace()
+		if parser.pos >= len(parser.data) {
+			return "", parser.errorf("missing value")
+		}
+		switch parser.data[parser.pos] {
+		case '{', '"':
+			part, err := parser.delimited()
+			if err != nil {
+				return "", err
+			}
+			value.WriteString(part)
+		default:
+			name := parser.identifier()
+			if name == "" {
+				return "", parser.errorf("missing value")
+			}
+			if macro, ok := parser.strings[strings.ToLower(name)]; ok {
+				value.WriteString(macro)
+			} else if month, ok := bibMonths[strings.ToLower(name)]; ok {
+				value.WriteString(month)
+			} else if _, err := strconv.Atoi(name); err == nil {
+				value.WriteString(name)
+			} else {
+				return "", parser.errorf("undefined string %q", name)
+			}
+		}
+		parser.skipSpace()
+		if parser.pos >= len(parser.data) || parser.data[parser.pos] != '#' {
+			return value.String(), nil
+		}
+		parser.pos++
+	}
+}
+
+// Parse the entries of a BibTeX file by key. @string macros are expanded and
+// @comment and @preamble blocks are skipped, as is text outside of entries.
+func parseBibTeX(data string) (map[string]BibEntry, error) {
+	parser := &bibParser{data: data, strings: map[string]string{}}
+	entries := map[string]BibEntry{}
+	for {
+		at := strings.IndexByte(parser.data[parser.pos:], '@')
+		if at == -1 {
+			return entries, nil
+		}
+		parser.pos += at + 1
+		entryType := strings.ToLower(parser.identifier())
+		parser.skipSpace()
+		// BibTeX treats an @ that does not start @type{ or @type( as part of a comment
+		if entryType == "" || parser.pos >= len(parser.data) || (parser.data[parser.pos] != '{' && parser.data[parser.pos] != '(') {
+			continue
+		}
+		closing := byte('}')
+		if parser.data[parser.pos] == '(' {
+			closing = ')'
+		}
+
+		switch entryType {
+		case "comment", "preamble":
+			if closing == '}' {
+				if _, err := parser.delimited(); err != nil {
+					return nil, err
+				}
+			} else if end := strings.IndexByte(parser.data[parser.pos:], ')'); end != -1 {
+				parser.pos += end + 1
+			}
+			continue
+		}
+		parser.pos++
+		parser.skipSpace()
+
+		entry := BibEntry{Type: entryType, Fields: map[string]string{}}
+		if entryType != "string" {
+			entry.Key = parser.identifier()
+			parser.skipSpace()
+			if entry.Key == "" || parser.pos >= len(parser.data) || parser.data[parser.pos] != ',' {
+				return nil, parser.errorf("expected a key and a comma after @%v", entryType)
+			}
+			parser.pos++
+		}
+		for {
+			parser.skipSpace()
+			if parser.pos < len(parser.data) && parser.data[parser.pos] == closing {
+				parser.pos++
+				break
+			}
+			name := strings.ToLower(parser.identifier())
+			parser.skipSpace()
+			if name == "" || parser.pos >= len(parser.data) || parser.data[parser.pos] != '=' {
+				return nil, parser.errorf("expected a field in %v", entry.Key)
+			}
+			parser.pos++
+			value, err := parser.value()
+			if err != nil {
+				return nil, err
+			}
+			entry.Fields[name] = value
+			parser.skipSpace()
+			if parser.pos < len(parser.data) && parser.data[parser.pos] == ',' {
+				parser.pos++
+			}
+		}
+
+		if entryType == "string" {
+			for name, value := range entry.Fields {
+				parser.strings[name] = value
+			}
+			continue
+		}
+		if _, ok := entries[entry.Key]; ok {
+			return nil, parser.errorf("duplicate key %v", entry.Key)
+		}
+		entries[entry.Key] = entry
+	}
+}
+
+// A field's value with braces and LaTeX escapes removed
+func (entry BibEntry) field(name string) string {
+	value := latexReplacer.Replace(entry.Fields[name])
+	value = strings.NewReplacer("{", "", "}", "").Replace(value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Author (or editor) last names. Names are written "Last, First" or "First Last" and separated by "and".
+func (entry BibEntry) lastNames() []string {
+	names := entry.field("author")
+	if names == "" {
+		names = entry.field("editor")
+	}
+	var lastNames []string
+	for _, name := range bibNameSeparatorPattern.Split(names, -1) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if last, _, found := strings.Cut(name, ","); found {
+			lastNames = append(lastNames, strings.TrimSpace(last))
+		} else {
+			fields := strings.Fields(name)
+			lastNames = append(lastNames, fields[len(fields)-1])
+		}
+	}
+	return lastNames
+}
+
+func (entry BibEntry) year() string {
+	if year := entry.field("year"); year != "" {
+		return year
+	}
+	return "n.d."
+}
+
+// Short author-year label, e.g. Knuth, 1984, Smith & Jones, 2020 or Smith et al., 2020
+func (entry BibEntry) authorYear() string {
+	names := entry.lastNames()
+	var authors string
+	switch len(names) {
+	case 0:
+		authors = entry.field("title")
+	case 1:
+		authors = names[0]
+	case 2:
+		authors = names[0] + " & " + names[1]
+	default:
+		authors = names[0] + " et al."
+	}
+	return authors + ", " + entry.year()
+}
+
+// Full reference, e.g. Knuth, Donald E. (1984). Literate Programming. *The Computer Journal*, 27(2), 97–111.
+func (entry BibEntry) reference() string {
+	authors := entry.field("author")
+	if authors == "" {
+		authors = entry.field("editor")
+	}
+	authors = strings.Join(bibNameSeparatorPattern.Split(authors, -1), "; ")
+	parts := []string{fmt.Sprintf("%v (%v).", authors, entry.year())}
+	if title := entry.field("title"); title != "" {
+		parts = append(parts, strings.TrimSuffix(title, ".")+".")
+	}
+	var container string
+	for _, name := range []string{"journal", "booktitle", "publisher", "institution", "school", "howpublished"} {
+		if container = entry.field(name); container != "" {
+			break
+		}
+	}
+	if container != "" {
+		details := "*" + container + "*"
+		if volume := entry.field("volume"); volume != "" {
+			details += ", " + volume
+			if number := entry.field("number"); number != "" {
+				details += "(" + number + ")"
+			}
+		}
+		if pages := entry.field("pages"); pages != "" {
+			details += ", " + pages
+		}
+		parts = append(parts, details+".")
+	}
+	// Links are written as autolinks so they are not bare urls
+	if doi := entry.field("doi"); doi != "" {
+		parts = append(parts, "<https://doi.org/"+doi+">")
+	} else if link := entry.field("url"); link != "" {
+		parts = append(parts, "<"+link+">")
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// Load the article folder's references.bib, returning nil when it does not have one
+func loadBibliography(articleFolder string) (map[string]BibEntry, error) {
+	data, err := os.ReadFile(filepath.Join(articleFolder, bibliographyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", bibliographyFile, err)
+	}
+	entries, err := parseBibTeX(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %v: %v", bibliographyFile, err)
+	}
+	return entries, nil
+}
+
+// Citation style from the front matter, CITATION_STYLE, or numeric
+func citationStyle(frontMatter FrontMatter) (string, error) {
+	style := frontMatter.CitationStyle
+	if style == "" {
+		style = os.Getenv("CITATION_STYLE")
+	}
+	switch style {
+	case "":
+		return citationNumeric, nil
+	case citationNumeric, citationAuthorYear:
+		return style, nil
+	}
+	return "", fmt.Errorf("Invalid citation style %q, expected numeric or author-year", style)
+}
+
+// Replace [@key] citations with numbered ([1]) or author-year ((Knuth, 1984)) citations and append
+// a references section listing the cited entries. Citations in code and links such as
+// [@handle](url) are left alone. Unknown keys, and every key when the article has no
+// references.bib, are reported as errors.
+func renderCitations(content string, bibliography map[string]BibEntry, style string) (string, []Diagnostic) {
+	var diagnostics []Diagnostic
+	var cited []string
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] || !strings.Contains(line, "[@") {
+			continue
+		}
+		masked, spans := maskInlineCode(line)
+		masked = citationPattern.ReplaceAllStringFunc(masked, func(citation string) string {
+			if strings.HasSuffix(citation, "(") || strings.HasSuffix(citation, "[") {
+				return citation
+			}
+			var labels []string
+			for _, part := range strings.Split(citation[1:len(citation)-1], ";") {
+				match := citationKeyPattern.FindStringSubmatch(strings.TrimSpace(part))
+				entry, ok := bibliography[match[1]]
+				if !ok {
+					message := fmt.Sprintf("Citation key %q is not in %v", match[1], bibliographyFile)
+					if bibliography == nil {
+						message = fmt.Sprintf("Citation key %q is cited but the article has no %v", match[1], bibliographyFile)
+					}
+					diagnostics = append(diagnostics, Diagnostic{Severity: severityError, Line: i + 1, Message: message})
+					return citation
+				}
+				if !slices.Contains(cited, entry.Key) {
+					cited = append(cited, entry.Key)
+				}
+				label := entry.authorYear()
+				if style == citationNumeric {
+					label = strconv.Itoa(slices.Index(cited, entry.Key) + 1)
+				}
+				if locator := strings.TrimSpace(match[2]); locator != "" {
+					label += ", " + locator
+				}
+				labels = append(labels, label)
+			}
+			if style == citationNumeric {
+				return "[" + strings.Join(labels, "; ") + "]"
+			}
+			return "(" + strings.Join(labels, "; ") + ")"
+		})
+		lines[i] = unmaskInlineCode(masked, spans)
+	}
+	content = strings.Join(lines, "\n")
+	if len(cited) == 0 {
+		return content, diagnostics
+	}
+
+	var references []string
+	if style == citationNumeric {
+		for i, key := range cited {
+			references = append(references, fmt.Sprintf("%d. %v", i+1, bibliography[key].reference()))
+		}
+	} else {
+		slices.SortFunc(cited, func(a string, b string) int {
+			return strings.Compare(bibliography[a].authorYear(), bibliography[b].authorYear())
+		})
+		for _, key := range cited {
+			references = append(references, "- "+bibliography[key].reference())
+		}
+	}
+	return content + "\n\n" + referencesHeading + "\n\n" + strings.Join(references, "\n"), diagnostics
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBibliography = `% Test references
+Maintained by docs@example.com, ask @ the docs team.
+@string{cj = "The Computer Journal"}
+
+@article{knuth1984,
+  author  = {Knuth, Donald E.},
+  title   = {{Literate} Programming},
+  journal = cj,
+  volume  = 27,
+  number  = {2},
+  pages   = {97--111},
+  month   = may,
+  year    = 1984,
+  doi     = {10.1093/comjnl/27.2.97}
+}
+
+@book{smith2020,
+  author    = "Jane Smith and Lee Jones",
+  title     = "Testing \& You",
+  publisher = {Example Press},
+  year      = {2020},
+}
+
+@comment{ignored @article{notreal, title={x}} }
+
+@inproceedings(brown2019,
+  author    = {Brown, A. and Green, B. and White, C.},
+  title     = {Distributed Things},
+  booktitle = {Proceedings of Things},
+  year      = {2019},
+  url       = {https://example.com/things}
+)
+`
+
+func TestParseBibTeX(t *testing.T) {
+	entries, err := parseBibTeX(testBibliography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf(`parseBibTeX found %d entries, but need 3`, len(entries))
+	}
+	knuth := entries["knuth1984"]
+	if knuth.Type != "article" || knuth.field("journal") != "The Computer Journal" || knuth.field("month") != "May" || knuth.field("title") != "Literate Programming" {
+		t.Fatalf(`parseBibTeX knuth1984 = %+v`, knuth)
+	}
+	if got := entries["smith2020"].field("title"); got != "Testing & You" {
+		t.Fatalf(`parseBibTeX smith2020 title = %q, but need "Testing & You"`, got)
+	}
+
+	// References are written without bare urls
+	content, _ := renderCitations("See [@knuth1984] and [@brown2019].", entries, citationNumeric)
+	config := LintConfig{Rules: map[string]LintRule{lintNoBareURLs: {Severity: severityError}}}
+	if diagnostics := config.lint(Article{Content: content}, nil); len(diagnostics) != 0 {
+		t.Fatalf(`references lint = %v, but need no bare urls`, diagnostics)
+	}
+
+	for _, invalid := range []string{"@article{nokey}", "@article{key, title = {unclosed}", "@article{key, journal = undefined}", "@book{a, title={x}}\n@book{a, title={y}}"} {
+		if _, err := parseBibTeX(invalid); err == nil {
+			t.Fatalf(`parseBibTeX(%q) should fail`, invalid)
+		}
+	}
+}
+
+func TestRenderCitationsNumeric(t *testing.T) {
+	bibliography, err := parseBibTeX(testBibliography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "As shown [@smith2020] and [@knuth1984, p. 99; @smith2020].\n\n```\n[@knuth1984]\n```\n[@missing]\nThanks [@jane](https://example.com/jane), [@lee][lee] and `[@knuth1984]`."
+	want := strings.Join([]string{
+		"As shown [1] and [2, p. 99; 1].",
+		"",
+		"```",
+		"[@knuth1984]",
+		"```",
+		"[@missing]",
+		"Thanks [@jane](https://example.com/jane), [@lee][lee] and `[@knuth1984]`.",
+		"",
+		"## References",
+		"",
+		"1. Jane Smith; Lee Jones (2020). Testing & You. *Example Press*.",
+		"2. Knuth, Donald E. (1984). Literate Programming. *The Computer Journal*, 27(2), 97–111. <https://doi.org/10.1093/comjnl/27.2.97>",
+	}, "\n")
+
+	got, diagnostics := renderCitations(content, bibliography, citationNumeric)
+	if got != want {
+		t.Fatalf("renderCitations =\n%v\nbut need\n%v", got, want)
+	}
+	wantDiagnostics := []Diagnostic{{Severity: severityError, Line: 6, Message: `Citation key "missing" is not in references.bib`}}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf(`renderCitations diagnostics = %v, but need %v`, diagnostics, wantDiagnostics)
+	}
+
+	// Without a references.bib every citation is an error
+	got, diagnostics = renderCitations("See [@smith2020] and [@jane](https://example.com/jane).", nil, citationNumeric)
+	wantDiagnostics = []Diagnostic{{Severity: severityError, Line: 1, Message: `Citation key "smith2020" is cited but the article has no references.bib`}}
+	if got != "See [@smith2020] and [@jane](https://example.com/jane)." || !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf(`renderCitations = %q, %v, but need %v`, got, diagnostics, wantDiagnostics)
+	}
+}
+
+func TestRenderCitationsAuthorYear(t *testing.T) {
+	bibliography, err := parseBibTeX(testBibliography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "Prior work [@smith2020; @brown2019] builds on [@knuth1984, ch. 2]."
+	want := strings.Join([]string{
+		"Prior work (Smith & Jones, 2020; Brown et al., 2019) builds on (Knuth, 1984, ch. 2).",
+		"",
+		"## References",
+		"",
+		"- Brown, A.; Green, B.; White, C. (2019). Distributed Things. *Proceedings of Things*. <https://example.com/things>",
+		"- Knuth, Donald E. (1984). Literate Programming. *The Computer Journal*, 27(2), 97–111. <https://doi.org/10.1093/comjnl/27.2.97>",
+		"- Jane Smith; Lee Jones (2020). Testing & You. *Example Press*.",
+	}, "\n")
+
+	got, diagnostics := renderCitations(content, bibliography, citationAuthorYear)
+	if got != want || len(diagnostics) != 0 {
+		t.Fatalf("renderCitations =\n%v\n%v\nbut need\n%v", got, diagnostics, want)
+	}
+}
+
+func TestCreateArticlePayloadCitations(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "research")
+	writeTestFile(t, filepath.Join(folder, "research.md"), "---\ncitation_style: author-year\n---\nSee [@knuth1984].")
+	writeTestFile(t, filepath.Join(folder, bibliographyFile), testBibliography)
+
+	name, articleFile, photos, err := parseArticle(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(article.Content, "See (Knuth, 1984).\n\n## References\n\n- Knuth") {
+		t.Fatalf(`createArticlePayload content = %q, but need the rendered citation and references`, article.Content)
+	}
+
+	if err := os.Remove(filepath.Join(folder, bibliographyFile)); err != nil {
+		t.Fatal(err)
+	}
+	article, err = createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(article.Diagnostics) != 1 || article.Diagnostics[0].Severity != severityError || article.Diagnostics[0].Line != 4 {
+		t.Fatalf(`createArticlePayload diagnostics = %v, but need an error for the citation without a %v`, article.Diagnostics, bibliographyFile)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Build the url for an endpoint whose path is held in the given env variable
+func apiURL(endpointEnv string) (string, error) {
+	base_url, exists := os.LookupEnv("BASE_DOMAIN")
+	if !exists {
+		return "", fmt.Errorf("Base url does not exists, please set the BASE_DOMAIN env variable")
+	}
+	endpoint, exists := os.LookupEnv(endpointEnv)
+	if !exists {
+		return "", fmt.Errorf("Endpoint not provided, please set the %v env variable", endpointEnv)
+	}
+
+	env := os.Getenv("ENV")
+	if len(env) == 0 {
+		env = "PROD"
+	}
+	protocol := "https://"
+	if env == "DEV" {
+		protocol = "http://"
+	}
+	return protocol + base_url + "/" + endpoint, nil
+}
+
+// Send a request with basic authentication, encoding the payload as JSON when one is given
+func sendAuthorizedRequest(method string, url string, payload any) (*http.Response, error) {
+	logger.Debug(fmt.Sprintf("Sending %v request to: %v", method, url))
+	user := os.Getenv("USERNAME")
+	pass := os.Getenv("PASSWORD")
+	auth := user + ":" + pass
+	basicAuth := b64.StdEncoding.EncodeToString([]byte(auth))
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("There was an error formatting the request body: %v", err)
+		}
+		body = bytes.NewBuffer(data)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("There was an error creating the request: %v", err)
+	}
+	req.Header.Add("Authorization", "Basic "+basicAuth)
+	if payload != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending %v request to %v: %v", method, url, err)
+	}
+	return resp, nil
+}
+
+// Send a request and decode a successful JSON response into result
+func sendAuthorizedJSON(method string, url string, payload any, result any) error {
+	resp, err := sendAuthorizedRequest(method, url, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading response body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Request to %v failed with status %v: %v", url, resp.Status, string(body))
+	}
+	if result == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("Error decoding response from %v: %v", url, err)
+	}
+	return nil
+}
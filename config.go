@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const folderConfigFile = "article.yaml"
+
+// How markdown files other than the main article file are handled
+const (
+	extraMarkdownIgnore  = "ignore"
+	extraMarkdownInclude = "include"
+	extraMarkdownArticle = "article"
+)
+
+// FolderConfig is read from an optional article.yaml file in the article folder
+type FolderConfig struct {
+	// Markdown file to publish, relative to the article folder
+	Main string `yaml:"main"`
+	// One of ignore, include (appended to the main article) or article (uploaded separately)
+	ExtraMarkdown string `yaml:"extra_markdown"`
+	// Language of the primary article when the folder holds translations
+	DefaultLanguage string `yaml:"default_language"`
+	// Publishing state for the folder's articles, overridden by front matter
+	Status    string `yaml:"status"`
+	PublishAt string `yaml:"publish_at"`
+}
+
+func loadFolderConfig(articleFolder string) (FolderConfig, error) {
+	config := FolderConfig{ExtraMarkdown: extraMarkdownIgnore}
+	data, err := os.ReadFile(filepath.Join(articleFolder, folderConfigFile))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("Error reading %v: %v", folderConfigFile, err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("Error parsing %v: %v", folderConfigFile, err)
+	}
+	if config.ExtraMarkdown == "" {
+		config.ExtraMarkdown = extraMarkdownIgnore
+	}
+	switch config.ExtraMarkdown {
+	case extraMarkdownIgnore, extraMarkdownInclude, extraMarkdownArticle:
+	default:
+		return config, fmt.Errorf("Invalid extra_markdown value %q in %v, expected ignore, include or article", config.ExtraMarkdown, folderConfigFile)
+	}
+	return config, nil
+}
+
+// Pick the markdown file to publish. The file named in the folder config wins,
+// then index.md, then the only markdown file in the folder. Anything else is ambiguous.
+func selectMainFile(articleFolder string, markdownFiles []string, config FolderConfig) (string, error) {
+	if config.Main != "" {
+		if !slices.Contains(markdownFiles, config.Main) {
+			return "", fmt.Errorf("Main article file %v set in %v does not exist in %v", config.Main, folderConfigFile, articleFolder)
+		}
+		return config.Main, nil
+	}
+	if slices.Contains(markdownFiles, "index.md") {
+		return "index.md", nil
+	}
+	switch len(markdownFiles) {
+	case 0:
+		return "", fmt.Errorf("Folder must contain an article written as a `.md` or `.ipynb` file")
+	case 1:
+		return markdownFiles[0], nil
+	}
+	primary, translated, err := selectPrimaryTranslation(articleFolder, markdownFiles, config)
+	if err != nil {
+		return "", err
+	}
+	if translated {
+		return primary, nil
+	}
+	sorted := slices.Sorted(slices.Values(markdownFiles))
+	return "", fmt.Errorf("Folder %v contains multiple markdown files (%v), add an index.md or set `main` in %v",
+		articleFolder, strings.Join(sorted, ", "), folderConfigFile)
+}
+
+// List the markdown files in the article folder other than the main article file, in name order
+func findExtraMarkdown(articleFolder string, articleFile string) ([]string, error) {
+	files, err := os.ReadDir(articleFolder)
+	if err != nil {
+		return nil, err
+	}
+	translations, err := findTranslations(articleFolder, articleFile)
+	if err != nil {
+		return nil, err
+	}
+	var extras []string
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".md" || file.Name() == filepath.Base(articleFile) {
+			continue
+		}
+		if slices.Contains(translations, filepath.Join(articleFolder, file.Name())) {
+			continue
+		}
+		extras = append(extras, filepath.Join(articleFolder, file.Name()))
+	}
+	return extras, nil
+}
+
+// Markdown files that should be uploaded as their own articles
+func findSubArticles(articleFolder string, articleFile string) ([]string, error) {
+	config, err := loadFolderConfig(articleFolder)
+	if err != nil {
+		return nil, err
+	}
+	extras, err := findExtraMarkdown(articleFolder, articleFile)
+	if err != nil {
+		return nil, err
+	}
+	if config.ExtraMarkdown == extraMarkdownIgnore && len(extras) > 0 {
+		logger.Warn("Ignoring markdown files that are not the main article", "files", extras)
+	}
+	if config.ExtraMarkdown != extraMarkdownArticle {
+		return nil, nil
+	}
+	// Articles are matched on the server by name, so a sub-article named like the main
+	// article would overwrite it
+	articleName, err := markdownName(articleFolder, articleFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, extra := range extras {
+		if strings.TrimSuffix(filepath.Base(extra), ".md") == articleName {
+			return nil, fmt.Errorf("Sub-article %v has the same name as the main article %v, rename one of them", extra, articleFile)
+		}
+	}
+	return extras, nil
+}
+
+// Markdown files whose content should be appended to the article file. Only files in the
+// article's language are included, files without a language being in the default language.
+func findIncludedMarkdown(articleFolder string, articleFile string) ([]string, error) {
+	config, err := loadFolderConfig(articleFolder)
+	if err != nil {
+		return nil, err
+	}
+	if config.ExtraMarkdown != extraMarkdownInclude {
+		return nil, nil
+	}
+	extras, err := findExtraMarkdown(articleFolder, articleFile)
+	if err != nil {
+		return nil, err
+	}
+	language, err := markdownLanguage(articleFile)
+	if err != nil {
+		return nil, err
+	}
+	if language == "" {
+		language = defaultLanguage(config)
+	}
+	var includes []string
+	for _, extra := range extras {
+		extraLanguage, err := markdownLanguage(extra)
+		if err != nil {
+			return nil, err
+		}
+		if extraLanguage == "" {
+			extraLanguage = defaultLanguage(config)
+		}
+		if extraLanguage == language {
+			includes = append(includes, extra)
+		}
+	}
+	return includes, nil
+}
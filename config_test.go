@@ -0,0 +1,108 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseArticlePrefersIndex(t *testing.T) {
+	articleFolder := filepath.Join(t.TempDir(), "intro_to_go")
+	writeTestFile(t, filepath.Join(articleFolder, "index.md"), "Main article")
+	writeTestFile(t, filepath.Join(articleFolder, "README.md"), "Readme")
+
+	name, article, _, err := parseArticle(articleFolder)
+	if err != nil || name != "intro_to_go" || article != filepath.Join(articleFolder, "index.md") {
+		t.Fatalf(`parseArticle(%v) = %q, %q, %v, but need "intro_to_go", index.md`, articleFolder, name, article, err)
+	}
+}
+
+func TestParseArticleConfigMain(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "main: post.md\n")
+	writeTestFile(t, filepath.Join(articleFolder, "post.md"), "Main article")
+	writeTestFile(t, filepath.Join(articleFolder, "notes.md"), "Notes")
+
+	name, article, _, err := parseArticle(articleFolder)
+	if err != nil || name != "post" || article != filepath.Join(articleFolder, "post.md") {
+		t.Fatalf(`parseArticle(%v) = %q, %q, %v, but need "post", post.md`, articleFolder, name, article, err)
+	}
+}
+
+func TestParseArticleAmbiguous(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "post.md"), "Main article")
+	writeTestFile(t, filepath.Join(articleFolder, "notes.md"), "Notes")
+
+	if name, article, _, err := parseArticle(articleFolder); err == nil {
+		t.Fatalf(`parseArticle(%v) = %q, %q, expected an error for an ambiguous folder`, articleFolder, name, article)
+	}
+}
+
+func TestCreateArticlePayloadIncludes(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "extra_markdown: include\n")
+	writeTestFile(t, filepath.Join(articleFolder, "index.md"), "Main article")
+	writeTestFile(t, filepath.Join(articleFolder, "b.md"), "---\nattachments: []\n---\nSecond")
+	writeTestFile(t, filepath.Join(articleFolder, "a.md"), "First")
+
+	name, articleFile, photos, err := parseArticle(articleFolder)
+	if err != nil {
+		t.Fatalf("There was an error: %v", err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	want := "Main article\n\nFirst\n\nSecond"
+	if err != nil || article.Content != want {
+		t.Fatalf(`createArticlePayload content = %q, %v, but need %q`, article.Content, err, want)
+	}
+}
+
+func TestFindSubArticles(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "main: series.md\nextra_markdown: article\n")
+	writeTestFile(t, filepath.Join(articleFolder, "series.md"), "Series")
+	writeTestFile(t, filepath.Join(articleFolder, "part_1.md"), "Part 1")
+
+	subArticles, err := findSubArticles(articleFolder, filepath.Join(articleFolder, "series.md"))
+	if err != nil || len(subArticles) != 1 || subArticles[0] != filepath.Join(articleFolder, "part_1.md") {
+		t.Fatalf(`findSubArticles(%v) = %v, %v, but need [part_1.md]`, articleFolder, subArticles, err)
+	}
+}
+
+func TestFindSubArticlesNameCollision(t *testing.T) {
+	articleFolder := filepath.Join(t.TempDir(), "foo")
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "extra_markdown: article\n")
+	writeTestFile(t, filepath.Join(articleFolder, "index.md"), "Main article")
+	writeTestFile(t, filepath.Join(articleFolder, "foo.md"), "Same name")
+
+	if subArticles, err := findSubArticles(articleFolder, filepath.Join(articleFolder, "index.md")); err == nil {
+		t.Fatalf(`findSubArticles(%v) = %v, expected an error for a sub-article named like the main article`, articleFolder, subArticles)
+	}
+}
+
+func TestFindIncludedMarkdownMatchesLanguage(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "main: post.en.md\nextra_markdown: include\n")
+	writeTestFile(t, filepath.Join(articleFolder, "post.en.md"), "Hello")
+	writeTestFile(t, filepath.Join(articleFolder, "post.de.md"), "Hallo")
+	writeTestFile(t, filepath.Join(articleFolder, "appendix.md"), "Appendix")
+	writeTestFile(t, filepath.Join(articleFolder, "appendix.de.md"), "Anhang")
+
+	cases := map[string]string{
+		"post.en.md": filepath.Join(articleFolder, "appendix.md"),
+		"post.de.md": filepath.Join(articleFolder, "appendix.de.md"),
+	}
+	for file, want := range cases {
+		includes, err := findIncludedMarkdown(articleFolder, filepath.Join(articleFolder, file))
+		if err != nil || len(includes) != 1 || includes[0] != want {
+			t.Fatalf(`findIncludedMarkdown(%v) = %v, %v, but need [%v]`, file, includes, err, want)
+		}
+	}
+}
+
+func TestLoadFolderConfigInvalid(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "extra_markdown: merge\n")
+	if _, err := loadFolderConfig(articleFolder); err == nil {
+		t.Fatalf(`loadFolderConfig expected an error for an invalid extra_markdown value`)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	coverImageName = "cover"
+	// Open Graph cards are 1200x630
+	coverWidth  = 1200
+	coverHeight = 630
+	coverMargin = 80
+	// Template drawn behind generated covers, relative to the repository root
+	coverTemplateFile = "og_template.png"
+)
+
+// Find the cover image among the article's photos. The `cover` front matter names a photo,
+// otherwise a photo called cover (photos/cover.jpg, photos/cover.png, ...) is used.
+func findCoverImage(frontMatter FrontMatter, images []Image) (string, error) {
+	name := coverImageName
+	if frontMatter.Cover != "" {
+		name = strings.TrimSuffix(filepath.Base(frontMatter.Cover), filepath.Ext(frontMatter.Cover))
+	}
+	for _, image := range images {
+		if image.Filename == name {
+			return image.Filename, nil
+		}
+	}
+	if frontMatter.Cover != "" {
+		return "", fmt.Errorf("Cover image %v is not a supported image in the photos folder", frontMatter.Cover)
+	}
+	return "", nil
+}
+
+// Parse a hex colour such as #1e293b
+func parseHexColor(hex string) (color.RGBA, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(strings.TrimPrefix(hex, "#")) != 6 {
+		return color.RGBA{}, fmt.Errorf("Invalid colour %q, expected a hex colour like #1e293b", hex)
+	}
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, nil
+}
+
+func envColor(name string, fallback string) (color.RGBA, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		value = fallback
+	}
+	return parseHexColor(value)
+}
+
+// Break text into lines that fit within width when drawn with face
+func wrapText(face font.Face, text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && font.MeasureString(face, candidate).Ceil() > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Draw an Open Graph card with the article title on the branded background. The background is
+// og_template.png at the repository root when it exists, otherwise the OG_BACKGROUND colour.
+func generateCoverImage(title string, root string) (Image, error) {
+	background, err := envColor("OG_BACKGROUND", "#1e293b")
+	if err != nil {
+		return Image{}, err
+	}
+	textColor, err := envColor("OG_TEXT_COLOR", "#ffffff")
+	if err != nil {
+		return Image{}, err
+	}
+
+	card := image.NewRGBA(image.Rect(0, 0, coverWidth, coverHeight))
+	draw.Draw(card, card.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+	if templateFile, err := os.Open(filepath.Join(root, coverTemplateFile)); err == nil {
+		template, _, err := image.Decode(templateFile)
+		templateFile.Close()
+		if err != nil {
+			return Image{}, fmt.Errorf("Error decoding %v: %v", coverTemplateFile, err)
+		}
+		draw.Draw(card, card.Bounds(), template, template.Bounds().Min, draw.Over)
+	}
+
+	parsed, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return Image{}, fmt.Errorf("Error loading cover font: %v", err)
+	}
+	// Use the largest size the title fits on four lines at
+	var face font.Face
+	var lines []string
+	var size float64
+	for _, size = range []float64{72, 60, 48, 40} {
+		face, err = opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return Image{}, fmt.Errorf("Error loading cover font: %v", err)
+		}
+		lines = wrapText(face, title, coverWidth-2*coverMargin)
+		if len(lines) <= 4 || size == 40 {
+			break
+		}
+		face.Close()
+	}
+	defer face.Close()
+
+	lineHeight := int(size * 1.25)
+	top := (coverHeight-lineHeight*len(lines))/2 + int(size)
+	drawer := &font.Drawer{Dst: card, Src: &image.Uniform{textColor}, Face: face}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(coverMargin, top+i*lineHeight)
+		drawer.DrawString(line)
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, card); err != nil {
+		return Image{}, fmt.Errorf("Error encoding cover image: %v", err)
+	}
+	data := b64.StdEncoding.EncodeToString(encoded.Bytes())
+	return Image{Filename: coverImageName, Data: &data, ContentType: "image/png", Width: coverWidth, Height: coverHeight, Hash: imageHash(encoded.Bytes())}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"image/png"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindCoverImage(t *testing.T) {
+	images := []Image{{Filename: "diagram"}, {Filename: "cover"}, {Filename: "hero"}}
+	if cover, err := findCoverImage(FrontMatter{}, images); err != nil || cover != "cover" {
+		t.Fatalf(`findCoverImage by convention = %q, %v, but need "cover"`, cover, err)
+	}
+	if cover, err := findCoverImage(FrontMatter{Cover: "photos/hero.jpg"}, images); err != nil || cover != "hero" {
+		t.Fatalf(`findCoverImage from front matter = %q, %v, but need "hero"`, cover, err)
+	}
+	if cover, err := findCoverImage(FrontMatter{Cover: "missing.png"}, images); err == nil {
+		t.Fatalf(`findCoverImage = %q, expected an error for a missing cover`, cover)
+	}
+	if cover, err := findCoverImage(FrontMatter{}, images[:1]); err != nil || cover != "" {
+		t.Fatalf(`findCoverImage without a cover = %q, %v, but need no cover`, cover, err)
+	}
+}
+
+func TestCreateArticlePayloadGeneratesCover(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	articleFile := filepath.Join(root, "intro", "intro.md")
+	writeTestFile(t, articleFile, "Hello")
+
+	article, err := createArticlePayload("a_rather_long_article_title_that_needs_to_wrap_over_several_lines_of_the_card", articleFile, "")
+	if err != nil {
+		t.Fatalf(`createArticlePayload returned unexpected error: %v`, err)
+	}
+	if article.CoverImage != "cover" || len(article.Images) != 1 || article.Images[0].ContentType != "image/png" {
+		t.Fatalf(`createArticlePayload cover = %q, images = %v, but need a generated cover`, article.CoverImage, article.Images)
+	}
+	data, err := b64.StdEncoding.DecodeString(*article.Images[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := png.Decode(bytes.NewReader(data))
+	if err != nil || card.Bounds().Dx() != coverWidth || card.Bounds().Dy() != coverHeight {
+		t.Fatalf(`generated cover is not a %dx%d png: %v`, coverWidth, coverHeight, err)
+	}
+
+	// Generating covers can be turned off
+	t.Setenv("GENERATE_COVER", "false")
+	article, err = createArticlePayload("intro", articleFile, "")
+	if err != nil || article.CoverImage != "" || len(article.Images) != 0 {
+		t.Fatalf(`createArticlePayload with GENERATE_COVER=false = %q, %v, %v, but need no cover`, article.CoverImage, article.Images, err)
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	if c, err := parseHexColor("#1e293b"); err != nil || c.R != 0x1e || c.G != 0x29 || c.B != 0x3b {
+		t.Fatalf(`parseHexColor("#1e293b") = %v, %v`, c, err)
+	}
+	if _, err := parseHexColor("blue"); err == nil {
+		t.Fatalf(`parseHexColor("blue") expected an error`)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	githubactions "github.com/sethvargo/go-githubactions"
+)
+
+// Severities of problems found while preparing an article
+const (
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+// A Diagnostic is a problem found in an article. Warnings are reported and the article is
+// still uploaded, errors stop the upload.
+type Diagnostic struct {
+	Severity string
+	Message  string
+	File     string
+	Line     int
+}
+
+func (diagnostic Diagnostic) String() string {
+	if diagnostic.Line > 0 {
+		return fmt.Sprintf("%v:%d: %v: %v", diagnostic.File, diagnostic.Line, diagnostic.Severity, diagnostic.Message)
+	}
+	return fmt.Sprintf("%v: %v: %v", diagnostic.File, diagnostic.Severity, diagnostic.Message)
+}
+
+func hasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == severityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Report diagnostics as GitHub annotations on the offending file and line
+func reportDiagnostics(action *githubactions.Action, diagnostics []Diagnostic) {
+	for _, diagnostic := range diagnostics {
+		fields := map[string]string{"file": diagnostic.File}
+		if diagnostic.Line > 0 {
+			fields["line"] = strconv.Itoa(diagnostic.Line)
+		}
+		annotated := action.WithFieldsMap(fields)
+		if diagnostic.Severity == severityError {
+			logger.Error("Article validation failed", "diagnostic", diagnostic.String())
+			annotated.Errorf("%v", diagnostic.Message)
+		} else {
+			logger.Warn("Article validation warning", "diagnostic", diagnostic.String())
+			annotated.Warningf("%v", diagnostic.Message)
+		}
+	}
+}
+
+// Add a table of an article's diagnostics to the job summary
+func summarizeDiagnostics(action *githubactions.Action, title string, diagnostics []Diagnostic) {
+	if len(diagnostics) == 0 {
+		return
+	}
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "### %v\n\n| Severity | Location | Problem |\n| --- | --- | --- |\n", title)
+	for _, diagnostic := range diagnostics {
+		location := diagnostic.File
+		if diagnostic.Line > 0 {
+			location += fmt.Sprintf(":%d", diagnostic.Line)
+		}
+		message := strings.ReplaceAll(diagnostic.Message, "|", "\\|")
+		fmt.Fprintf(&summary, "| %v | `%v` | %v |\n", diagnostic.Severity, location, message)
+	}
+	action.AddStepSummary(summary.String())
+}
+
+// A SourceLine is the file and line a line of an article's content was written on
+type SourceLine struct {
+	File string
+	Line int
+}
+
+// A SourceMap follows the lines of an article's content back to where they were written as
+// includes, shortcodes, variables and callouts expand, so problems found in the expanded
+// content are reported on the line that produced them
+type SourceMap struct {
+	sources []SourceLine
+}
+
+// Map content that starts on firstLine of file
+func newSourceMap(content string, file string, firstLine int) *SourceMap {
+	sourceMap := &SourceMap{}
+	for i := range strings.Count(content, "\n") + 1 {
+		sourceMap.sources = append(sourceMap.sources, SourceLine{File: file, Line: firstLine + i})
+	}
+	return sourceMap
+}
+
+// Follow the content after an expansion pass rewrote it, given the line of the previous content,
+// counting from 0, that each of its lines came from
+func (sourceMap *SourceMap) follow(origins []int) {
+	sources := make([]SourceLine, 0, len(origins))
+	for _, origin := range origins {
+		sources = append(sources, sourceMap.sources[min(origin, len(sourceMap.sources)-1)])
+	}
+	if len(sources) == 0 {
+		sources = sourceMap.sources[:1]
+	}
+	sourceMap.sources = sources
+}
+
+// Follow content whose lines were kept and that had lines appended to it, such as a references
+// section. The appended lines take the source of the last line.
+func (sourceMap *SourceMap) extend(content string) {
+	for len(sourceMap.sources) < strings.Count(content, "\n")+1 {
+		sourceMap.sources = append(sourceMap.sources, sourceMap.sources[len(sourceMap.sources)-1])
+	}
+}
+
+// Follow content with an included file appended to it, whose text starts on firstLine of file
+func (sourceMap *SourceMap) include(content string, file string, firstLine int, text string) {
+	lines := strings.Count(content, "\n") + 1
+	included := strings.Count(text, "\n") + 1
+	for i := len(sourceMap.sources); i < lines; i++ {
+		if offset := i - (lines - included); offset >= 0 {
+			sourceMap.sources = append(sourceMap.sources, SourceLine{File: file, Line: firstLine + offset})
+		} else {
+			sourceMap.sources = append(sourceMap.sources, sourceMap.sources[len(sourceMap.sources)-1])
+		}
+	}
+}
+
+// Where a line of the content (counting from 1) was written
+func (sourceMap *SourceMap) source(line int) SourceLine {
+	if line < 1 || line > len(sourceMap.sources) {
+		return SourceLine{File: sourceMap.sources[0].File}
+	}
+	return sourceMap.sources[line-1]
+}
+
+// Place diagnostics found in the content at the file and line they were written on.
+// Diagnostics without a line are placed in file.
+func (sourceMap *SourceMap) locate(diagnostics []Diagnostic, file string) []Diagnostic {
+	for i := range diagnostics {
+		diagnostics[i].File = file
+		if diagnostics[i].Line > 0 {
+			source := sourceMap.source(diagnostics[i].Line)
+			diagnostics[i].File, diagnostics[i].Line = source.File, source.Line
+		}
+	}
+	return diagnostics
+}
+
+// A lineTracker builds rewritten content, recording for every line it writes the line of the
+// original content, counting from 0, that it came from
+type lineTracker struct {
+	source  string
+	text    strings.Builder
+	origins []int
+}
+
+// Write text whose lines come one after another from the original, starting at line
+func (tracker *lineTracker) writeLines(text string, line int) {
+	tracker.add(text, line, true)
+}
+
+// Write text that replaces the original at line, so all of its lines come from that line
+func (tracker *lineTracker) write(text string, line int) {
+	tracker.add(text, line, false)
+}
+
+// Copy the original content from offset start to end
+func (tracker *lineTracker) copy(start int, end int) {
+	tracker.writeLines(tracker.source[start:end], strings.Count(tracker.source[:start], "\n"))
+}
+
+// Start a new line of text that comes from line
+func (tracker *lineTracker) writeLine(text string, line int) {
+	if len(tracker.origins) > 0 {
+		tracker.text.WriteString("\n")
+	}
+	tracker.origins = append(tracker.origins, line)
+	tracker.text.WriteString(text)
+}
+
+// Write another tracker's content, which was rewritten from the same original
+func (tracker *lineTracker) append(other *lineTracker) {
+	if len(other.origins) == 0 {
+		return
+	}
+	origins := other.origins
+	// The first line of other continues the line being written
+	if len(tracker.origins) > 0 {
+		if tracker.atLineStart() && other.text.Len() > 0 && other.text.String()[0] != '\n' {
+			tracker.origins[len(tracker.origins)-1] = origins[0]
+		}
+		origins = origins[1:]
+	}
+	tracker.text.WriteString(other.text.String())
+	tracker.origins = append(tracker.origins, origins...)
+}
+
+// Whether nothing has been written to the current line yet
+func (tracker *lineTracker) atLineStart() bool {
+	return tracker.text.Len() == 0 || strings.HasSuffix(tracker.text.String(), "\n")
+}
+
+func (tracker *lineTracker) add(text string, line int, consecutive bool) {
+	if len(tracker.origins) == 0 {
+		tracker.origins = append(tracker.origins, line)
+	} else if text != "" && text[0] != '\n' && tracker.atLineStart() {
+		// Text that starts a line gives the line its origin
+		tracker.origins[len(tracker.origins)-1] = line
+	}
+	for range strings.Count(text, "\n") {
+		if consecutive {
+			line++
+		}
+		tracker.origins = append(tracker.origins, line)
+	}
+	tracker.text.WriteString(text)
+}
+
+func (tracker *lineTracker) String() string {
+	return tracker.text.String()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	sourceMap := newSourceMap("one\ntwo\nthree", "post.md", 4)
+	sourceMap.include("one\ntwo\nthree\n\nincluded", "part.md", 2, "included")
+	sourceMap.follow([]int{0, 1, 1, 2, 4})
+	sourceMap.extend("a\nb\nc\nd\ne\n\nreferences")
+	want := []SourceLine{{"post.md", 4}, {"post.md", 5}, {"post.md", 5}, {"post.md", 6}, {"part.md", 2}, {"part.md", 2}, {"part.md", 2}}
+	if !reflect.DeepEqual(sourceMap.sources, want) {
+		t.Fatalf(`SourceMap sources = %v, but need %v`, sourceMap.sources, want)
+	}
+
+	diagnostics := sourceMap.locate([]Diagnostic{{Line: 5}, {Line: 0}, {Line: 99}}, "post.md")
+	wantDiagnostics := []Diagnostic{{File: "part.md", Line: 2}, {File: "post.md"}, {File: "post.md"}}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf(`locate = %v, but need %v`, diagnostics, wantDiagnostics)
+	}
+
+	// Content expanded to nothing keeps the first source
+	sourceMap.follow(nil)
+	if got := sourceMap.source(1); got != (SourceLine{"post.md", 4}) {
+		t.Fatalf(`source = %v, but need post.md:4`, got)
+	}
+}
+
+func TestLineTracker(t *testing.T) {
+	source := "Intro\nSee {{< x >}} here\nEnd"
+	tag := strings.Index(source, "{{<")
+	tracker := &lineTracker{source: source}
+	tracker.copy(0, tag)
+	tracker.write("A\nB", 1)
+	tracker.copy(strings.Index(source, " here"), len(source))
+	if tracker.String() != "Intro\nSee A\nB here\nEnd" || !reflect.DeepEqual(tracker.origins, []int{0, 1, 1, 2}) {
+		t.Fatalf(`lineTracker = %q %v, but need the replaced lines from line 1`, tracker.String(), tracker.origins)
+	}
+
+	// Text that starts a line gives it its origin, and appended content continues the current line
+	tracker = &lineTracker{}
+	tracker.writeLine("", 3)
+	tracker.write("<div>\n", 3)
+	tracker.writeLines("body\nmore", 5)
+	inner := &lineTracker{}
+	inner.writeLine(" tail", 9)
+	inner.writeLine("next", 10)
+	tracker.append(inner)
+	if tracker.String() != "<div>\nbody\nmore tail\nnext" || !reflect.DeepEqual(tracker.origins, []int{3, 5, 6, 10}) {
+		t.Fatalf(`lineTracker = %q %v, but need [3 5 6 10]`, tracker.String(), tracker.origins)
+	}
+}
+
+func TestCreateArticlePayloadSourceLines(t *testing.T) {
+	t.Setenv("GENERATE_COVER", "false")
+	folder := filepath.Join(t.TempDir(), "lines")
+	writeTestFile(t, filepath.Join(folder, "lines.md"), strings.Join([]string{
+		"---",
+		"title: Lines",
+		"---",
+		`{{< callout tip >}}`,
+		"A tip",
+		`{{< /callout >}}`,
+		"",
+		"> [!NOTE]",
+		"> See [@first].",
+		"",
+		"Then [@second].",
+	}, "\n"))
+
+	name, articleFile, photos, err := parseArticle(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, diagnostic := range article.Diagnostics {
+		if strings.HasPrefix(diagnostic.Message, "Citation key") {
+			lines = append(lines, diagnostic.Line)
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{9, 11}) {
+		t.Fatalf(`createArticlePayload diagnostics = %v, but need the citations on lines 9 and 11`, article.Diagnostics)
+	}
+}
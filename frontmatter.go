@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter is the optional YAML block delimited by `---` lines at the top of an article
+type FrontMatter struct {
+	Title       string   `yaml:"title"`
+	Slug        string   `yaml:"slug"`
+	Lang        string   `yaml:"lang"`
+	Author      string   `yaml:"author"`
+	Tags        []string `yaml:"tags"`
+	Category    string   `yaml:"category"`
+	Excerpt     string   `yaml:"excerpt"`
+	Cover       string   `yaml:"cover"`
+	WordCount   *int     `yaml:"word_count"`
+	ReadingTime *int     `yaml:"reading_time"`
+	Status      string   `yaml:"status"`
+	PublishAt   string   `yaml:"publish_at"`
+	Attachments []string `yaml:"attachments"`
+	// numeric or author-year
+	CitationStyle string `yaml:"citation_style"`
+	// Template variables for the article's content, merged over the site-wide variables
+	Variables map[string]any `yaml:"variables"`
+	// Alt text, captions and order of photos, over those in photos/captions.yaml
+	Images map[string]ImageMetadata `yaml:"images"`
+}
+
+// Split the YAML front matter block from the markdown body. Articles without
+// front matter have an empty block and their content as the body.
+func splitFrontMatter(content string) (string, string, error) {
+	normalised := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalised, "---\n") {
+		return "", content, nil
+	}
+	rest := normalised[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end == -1 {
+		return "", content, fmt.Errorf("Front matter is missing its closing `---` line")
+	}
+	return rest[:end], strings.TrimPrefix(rest[end+len("\n---"):], "\n"), nil
+}
+
+// Line of a file its markdown body starts on once leading blank lines are trimmed
+func bodyLine(data string, body string) int {
+	leading := body[:len(body)-len(strings.TrimLeft(body, " \t\r\n"))]
+	return strings.Count(data, "\n") - strings.Count(body, "\n") + strings.Count(leading, "\n") + 1
+}
+
+// Split an article into its front matter and markdown body. Articles without
+// front matter are returned unchanged with an empty FrontMatter.
+func parseFrontMatter(content string) (FrontMatter, string, error) {
+	var frontMatter FrontMatter
+	block, body, err := splitFrontMatter(content)
+	if err != nil {
+		return frontMatter, content, err
+	}
+	if err := yaml.Unmarshal([]byte(block), &frontMatter); err != nil {
+		return frontMatter, content, fmt.Errorf("Error parsing front matter: %v", err)
+	}
+	return frontMatter, body, nil
+}
+
+// The raw front matter fields by name, including ones FrontMatter does not know about
+func frontMatterFields(content string) (map[string]any, error) {
+	fields := map[string]any{}
+	block, _, err := splitFrontMatter(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal([]byte(block), &fields); err != nil {
+		return nil, fmt.Errorf("Error parsing front matter: %v", err)
+	}
+	return fields, nil
+}
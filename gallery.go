@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+	"gopkg.in/yaml.v3"
+)
+
+// Sidecar file in the photos folder with the alt text, caption and order of each photo
+const captionsFile = "captions.yaml"
+
+// ImageMetadata describes a photo, keyed by its file name with or without extension in
+// photos/captions.yaml or the images front matter. A plain string is read as the caption.
+type ImageMetadata struct {
+	Alt     string `yaml:"alt"`
+	Caption string `yaml:"caption"`
+	// Photos with an order are shown first, in that order, and the rest by file name
+	Order *int `yaml:"order"`
+}
+
+func (metadata *ImageMetadata) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&metadata.Caption)
+	}
+	type plain ImageMetadata
+	return node.Decode((*plain)(metadata))
+}
+
+// Name of a photo as it is uploaded, without its extension
+func photoKey(name string) string {
+	return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+}
+
+// Load the photo metadata from photos/captions.yaml, with the images front matter taking
+// precedence for each field it sets
+func loadImageMetadata(articlePhotos string, frontMatter FrontMatter) (map[string]ImageMetadata, error) {
+	sidecar := map[string]ImageMetadata{}
+	if articlePhotos != "" {
+		data, err := os.ReadFile(filepath.Join(articlePhotos, captionsFile))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("Error reading %v: %v", captionsFile, err)
+		}
+		if err := yaml.Unmarshal(data, &sidecar); err != nil {
+			return nil, fmt.Errorf("Error parsing %v: %v", captionsFile, err)
+		}
+	}
+	metadata := map[string]ImageMetadata{}
+	for name, photo := range sidecar {
+		metadata[photoKey(name)] = photo
+	}
+	for name, photo := range frontMatter.Images {
+		merged := metadata[photoKey(name)]
+		if photo.Alt != "" {
+			merged.Alt = photo.Alt
+		}
+		if photo.Caption != "" {
+			merged.Caption = photo.Caption
+		}
+		if photo.Order != nil {
+			merged.Order = photo.Order
+		}
+		metadata[photoKey(name)] = merged
+	}
+	return metadata, nil
+}
+
+// Compare photos by their display order: ordered photos first, then by name
+func compareDisplayOrder(metadata map[string]ImageMetadata, a string, b string) int {
+	orderA, orderB := metadata[photoKey(a)].Order, metadata[photoKey(b)].Order
+	switch {
+	case orderA != nil && orderB != nil && *orderA != *orderB:
+		return cmp.Compare(*orderA, *orderB)
+	case orderA != nil && orderB == nil:
+		return -1
+	case orderA == nil && orderB != nil:
+		return 1
+	}
+	return cmp.Compare(a, b)
+}
+
+// Apply the photo metadata to the images and number them in display order. Alt text from the
+// metadata replaces alt text taken from the content.
+func arrangeImages(images []Image, metadata map[string]ImageMetadata) {
+	slices.SortStableFunc(images, func(a Image, b Image) int {
+		return compareDisplayOrder(metadata, a.Filename, b.Filename)
+	})
+	for i := range images {
+		photo := metadata[images[i].Filename]
+		if photo.Alt != "" {
+			images[i].Alt = photo.Alt
+		}
+		images[i].Caption = photo.Caption
+		images[i].Order = i + 1
+	}
+}
+
+var (
+	svgTagPattern       = regexp.MustCompile(`(?is)<svg\b[^>]*>`)
+	svgAttributePattern = regexp.MustCompile(`(?i)\s(width|height|viewBox)\s*=\s*["']([^"']*)["']`)
+)
+
+// Width and height of an image in pixels, or zero when they cannot be read from the data
+func imageDimensions(contentType string, data []byte) (int, int) {
+	if contentType == "image/svg+xml" {
+		return svgDimensions(data)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
+
+// SVG dimensions from the width and height attributes, or else the view box
+func svgDimensions(data []byte) (int, int) {
+	tag := svgTagPattern.Find(data)
+	attributes := map[string]string{}
+	for _, match := range svgAttributePattern.FindAllSubmatch(tag, -1) {
+		attributes[strings.ToLower(string(match[1]))] = string(match[2])
+	}
+	width, widthErr := strconv.ParseFloat(strings.TrimSuffix(attributes["width"], "px"), 64)
+	height, heightErr := strconv.ParseFloat(strings.TrimSuffix(attributes["height"], "px"), 64)
+	if widthErr == nil && heightErr == nil {
+		return int(width), int(height)
+	}
+	viewBox := strings.Fields(strings.ReplaceAll(attributes["viewbox"], ",", " "))
+	if len(viewBox) == 4 {
+		width, widthErr = strconv.ParseFloat(viewBox[2], 64)
+		height, heightErr = strconv.ParseFloat(viewBox[3], 64)
+		if widthErr == nil && heightErr == nil {
+			return int(width), int(height)
+		}
+	}
+	return 0, 0
+}
+
+// Whether a file in the photos folder is an image that will be uploaded, judged by its extension
+func isPhotoFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, media := range supportedMediaTypes() {
+		if slices.Contains(media.Extensions, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// {{< gallery sunset "harbour.jpg" >}} groups photos into a gallery, or every photo in display
+// order without arguments. Photos are written as images with their caption as the title, in
+// a block the site renders as a gallery.
+func expandGallery(context shortcodeContext, shortcode Shortcode) (string, error) {
+	if context.Photos == "" {
+		return "", fmt.Errorf("Shortcode gallery needs a photos folder next to the article")
+	}
+	files, err := os.ReadDir(context.Photos)
+	if err != nil {
+		return "", fmt.Errorf("Error reading photos folder: %v", err)
+	}
+	var available []string
+	for _, file := range files {
+		if !file.IsDir() && isPhotoFile(file.Name()) {
+			available = append(available, file.Name())
+		}
+	}
+	photos := shortcode.Args
+	if len(photos) == 0 {
+		photos = slices.Clone(available)
+		slices.SortStableFunc(photos, func(a string, b string) int {
+			return compareDisplayOrder(context.Images, a, b)
+		})
+	}
+	if len(photos) == 0 {
+		return "", fmt.Errorf("Shortcode gallery found no photos in the photos folder")
+	}
+	var gallery strings.Builder
+	gallery.WriteString("<div class=\"gallery\">\n\n")
+	for _, photo := range photos {
+		if !slices.ContainsFunc(available, func(name string) bool { return name == photo || photoKey(name) == photo }) {
+			return "", fmt.Errorf("Gallery photo %q is not in the photos folder", photo)
+		}
+		metadata := context.Images[photoKey(photo)]
+		reference := fmt.Sprintf("![%v](%v", metadata.Alt, photoKey(photo))
+		if metadata.Caption != "" {
+			reference += " " + strconv.Quote(metadata.Caption)
+		}
+		gallery.WriteString(reference + ")\n")
+	}
+	gallery.WriteString("\n</div>")
+	return gallery.String(), nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadImageMetadata(t *testing.T) {
+	photos := filepath.Join(t.TempDir(), "photos")
+	writeTestFile(t, filepath.Join(photos, captionsFile), strings.Join([]string{
+		"harbour.jpg:",
+		"  alt: Boats moored in the harbour",
+		"  caption: The harbour at dawn",
+		"  order: 2",
+		"sunset: Sunset over the bay",
+	}, "\n"))
+	order := 1
+	frontMatter := FrontMatter{Images: map[string]ImageMetadata{"sunset.png": {Alt: "An orange sun", Order: &order}, "harbour": {Caption: "Morning"}}}
+
+	metadata, err := loadImageMetadata(photos, frontMatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	harbour, sunset := metadata["harbour"], metadata["sunset"]
+	if harbour.Alt != "Boats moored in the harbour" || harbour.Caption != "Morning" || harbour.Order == nil || *harbour.Order != 2 {
+		t.Fatalf("harbour metadata = %+v, but need the sidecar alt and order with the front matter caption", harbour)
+	}
+	if sunset.Alt != "An orange sun" || sunset.Caption != "Sunset over the bay" || sunset.Order == nil || *sunset.Order != 1 {
+		t.Fatalf("sunset metadata = %+v, but need the sidecar caption with the front matter alt and order", sunset)
+	}
+
+	writeTestFile(t, filepath.Join(photos, captionsFile), "- not a map")
+	if _, err := loadImageMetadata(photos, FrontMatter{}); err == nil {
+		t.Fatal("loadImageMetadata succeeded, but need an error for an invalid captions file")
+	}
+}
+
+func TestArrangeImages(t *testing.T) {
+	first, second := 1, 2
+	metadata := map[string]ImageMetadata{
+		"harbour": {Caption: "The harbour", Order: &second},
+		"sunset":  {Alt: "An orange sun", Order: &first},
+	}
+	images := []Image{{Filename: "beach", Alt: "Sand"}, {Filename: "harbour", Alt: "Boats"}, {Filename: "alley"}, {Filename: "sunset", Alt: "Sun"}}
+	arrangeImages(images, metadata)
+
+	var got []string
+	for _, image := range images {
+		got = append(got, strings.Join([]string{image.Filename, image.Alt, image.Caption, strconv.Itoa(image.Order)}, "|"))
+	}
+	want := []string{"sunset|An orange sun||1", "harbour|Boats|The harbour|2", "alley|||3", "beach|Sand||4"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("arrangeImages = %v, but need %v", got, want)
+	}
+}
+
+func TestImageDimensions(t *testing.T) {
+	pngData, err := b64.StdEncoding.DecodeString(testPNG(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		contentType string
+		data        string
+		width       int
+		height      int
+	}{
+		{"image/png", string(pngData), 2, 2},
+		{"image/svg+xml", `<svg xmlns="http://www.w3.org/2000/svg" width="120px" height="80"></svg>`, 120, 80},
+		{"image/svg+xml", `<svg viewBox="0 0 64.5 32" xmlns="http://www.w3.org/2000/svg"></svg>`, 64, 32},
+		{"image/svg+xml", `<svg xmlns="http://www.w3.org/2000/svg" width="100%"></svg>`, 0, 0},
+		{"image/avif", "not an image", 0, 0},
+	}
+	for _, c := range cases {
+		if width, height := imageDimensions(c.contentType, []byte(c.data)); width != c.width || height != c.height {
+			t.Errorf("imageDimensions(%v) = %d×%d, but need %d×%d", c.contentType, width, height, c.width, c.height)
+		}
+	}
+}
+
+func TestGalleryShortcode(t *testing.T) {
+	photos := filepath.Join(t.TempDir(), "photos")
+	for _, name := range []string{"beach.png", "harbour.jpg", "sunset.png"} {
+		writeTestFile(t, filepath.Join(photos, name), "")
+	}
+	writeTestFile(t, filepath.Join(photos, captionsFile), "")
+	first := 1
+	context := shortcodeContext{Photos: photos, Images: map[string]ImageMetadata{
+		"sunset":  {Alt: "An orange sun", Caption: `The "golden" hour`, Order: &first},
+		"harbour": {Alt: "Boats"},
+	}}
+
+	got, _, diagnostics := expandShortcodes("Photos:\n\n{{< gallery >}}", context)
+	want := "Photos:\n\n<div class=\"gallery\">\n\n![An orange sun](sunset \"The \\\"golden\\\" hour\")\n![](beach)\n![Boats](harbour)\n\n</div>"
+	if got != want || len(diagnostics) != 0 {
+		t.Fatalf("gallery =\n%v\n%v\nbut need\n%v", got, diagnostics, want)
+	}
+
+	got, _, _ = expandShortcodes(`{{< gallery "harbour.jpg" sunset >}}`, context)
+	if !strings.Contains(got, "![Boats](harbour)\n![An orange sun](sunset") {
+		t.Fatalf("gallery =\n%v\nbut need harbour then sunset", got)
+	}
+
+	_, _, diagnostics = expandShortcodes(`{{< gallery missing >}}`, context)
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, `"missing" is not in the photos folder`) {
+		t.Fatalf("gallery diagnostics = %v, but need an error for the missing photo", diagnostics)
+	}
+	_, _, diagnostics = expandShortcodes(`{{< gallery >}}`, shortcodeContext{})
+	if len(diagnostics) != 1 {
+		t.Fatalf("gallery diagnostics = %v, but need an error without a photos folder", diagnostics)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A GitAuthor is someone who has committed to an article file
+type GitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// GitMetadata is read from the history of an article file
+type GitMetadata struct {
+	CreatedAt    *time.Time
+	UpdatedAt    *time.Time
+	Authors      []GitAuthor
+	LatestAuthor *GitAuthor
+	RevisionNote string
+}
+
+// Payload field names for git metadata, which can be renamed with the GIT_METADATA_FIELDS
+// env variable, e.g. "created_at=first_published,revision_note=changelog"
+var gitMetadataFields = []string{"created_at", "updated_at", "authors", "revision_note"}
+
+// Root of the repository holding the articles. GITHUB_WORKSPACE is used in workflows,
+// otherwise it is the git top level of dir, falling back to dir itself.
+func repoRoot(dir string) string {
+	if workspace := os.Getenv("GITHUB_WORKSPACE"); workspace != "" {
+		return workspace
+	}
+	if root, err := runGit(dir, "rev-parse", "--show-toplevel"); err == nil {
+		return strings.TrimSpace(root)
+	}
+	return dir
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %v failed: %v %v", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// Whether dir is inside the work tree of a git repository
+func insideWorkTree(dir string) bool {
+	out, err := runGit(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Whether the repository holding dir is a shallow clone, whose history is cut off
+func shallowRepository(dir string) bool {
+	out, err := runGit(dir, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Read the commit history of an article file. The revision note describes the commit that
+// triggered the workflow (GITHUB_SHA) or HEAD when running locally. In a shallow clone the first
+// commit is not known, so created_at is left out.
+func readGitMetadata(articleFile string) (GitMetadata, error) {
+	var metadata GitMetadata
+	dir := filepath.Dir(articleFile)
+	log, err := runGit(dir, "log", "--follow", "--format=%H%x1f%aI%x1f%an%x1f%ae", "--", filepath.Base(articleFile))
+	if err != nil {
+		return metadata, err
+	}
+
+	seen := map[string]bool{}
+	lines := strings.Split(strings.TrimSpace(log), "\n")
+	// git log lists the newest commit first, authors are reported in order of first contribution
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Split(lines[i], "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return metadata, fmt.Errorf("Error parsing commit date %q: %v", fields[1], err)
+		}
+		if metadata.CreatedAt == nil {
+			metadata.CreatedAt = &date
+		}
+		metadata.UpdatedAt = &date
+		metadata.LatestAuthor = &GitAuthor{Name: fields[2], Email: fields[3]}
+		if !seen[strings.ToLower(fields[3])] {
+			seen[strings.ToLower(fields[3])] = true
+			metadata.Authors = append(metadata.Authors, GitAuthor{Name: fields[2], Email: fields[3]})
+		}
+	}
+	if metadata.CreatedAt != nil && shallowRepository(dir) {
+		logger.Warn("Repository is a shallow clone, created_at will not be sent and authors may be incomplete; check out with fetch-depth: 0", "article", articleFile)
+		metadata.CreatedAt = nil
+	}
+
+	revision := os.Getenv("GITHUB_SHA")
+	if revision == "" {
+		revision = "HEAD"
+	}
+	commit, err := runGit(dir, "log", "-1", "--format=%H%x1f%s", revision)
+	if err != nil {
+		logger.Debug("Could not read the triggering commit", "error", err)
+		return metadata, nil
+	}
+	if fields := strings.Split(strings.TrimSpace(commit), "\x1f"); len(fields) == 2 {
+		sha := fields[0]
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		metadata.RevisionNote = sha + ": " + fields[1]
+	}
+	return metadata, nil
+}
+
+// JSON names of the Article fields, which git metadata fields cannot be renamed to
+func articlePayloadFields() map[string]bool {
+	fields := map[string]bool{}
+	articleType := reflect.TypeOf(Article{})
+	for i := 0; i < articleType.NumField(); i++ {
+		name, _, _ := strings.Cut(articleType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// Map each git metadata field to the payload field name it is sent as
+func gitMetadataFieldNames() (map[string]string, error) {
+	names := map[string]string{}
+	for _, field := range gitMetadataFields {
+		names[field] = field
+	}
+	configured := os.Getenv("GIT_METADATA_FIELDS")
+	if strings.TrimSpace(configured) == "" {
+		return names, nil
+	}
+	for _, mapping := range strings.Split(configured, ",") {
+		field, name, found := strings.Cut(strings.TrimSpace(mapping), "=")
+		if _, known := names[field]; !found || !known || name == "" {
+			return nil, fmt.Errorf("Invalid GIT_METADATA_FIELDS entry %q, expected <field>=<name> for one of %v", mapping, gitMetadataFields)
+		}
+		names[field] = name
+	}
+	reserved := articlePayloadFields()
+	used := map[string]string{}
+	for _, field := range gitMetadataFields {
+		name := names[field]
+		if reserved[name] {
+			return nil, fmt.Errorf("Invalid GIT_METADATA_FIELDS entry %v=%v, %v is already an article field", field, name, name)
+		}
+		if other, ok := used[name]; ok {
+			return nil, fmt.Errorf("Invalid GIT_METADATA_FIELDS, %v and %v are both sent as %v", other, field, name)
+		}
+		used[name] = field
+	}
+	return names, nil
+}
+
+// Optional payload fields for the git metadata, keyed by their configured names
+func (metadata GitMetadata) payloadFields() (map[string]any, error) {
+	names, err := gitMetadataFieldNames()
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	if metadata.CreatedAt != nil {
+		fields[names["created_at"]] = metadata.CreatedAt
+	}
+	if metadata.UpdatedAt != nil {
+		fields[names["updated_at"]] = metadata.UpdatedAt
+	}
+	if len(metadata.Authors) > 0 {
+		authors := metadata.Authors
+		// Contributor emails are only sent when GIT_AUTHOR_EMAILS is true
+		if os.Getenv("GIT_AUTHOR_EMAILS") != "true" {
+			authors = make([]GitAuthor, len(metadata.Authors))
+			for i, author := range metadata.Authors {
+				authors[i] = GitAuthor{Name: author.Name}
+			}
+		}
+		fields[names["authors"]] = authors
+	}
+	if metadata.RevisionNote != "" {
+		fields[names["revision_note"]] = metadata.RevisionNote
+	}
+	return fields, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Commit everything in dir as the given author at the given time
+func commitTestRepo(t *testing.T, dir string, name string, email string, date string, message string) {
+	t.Helper()
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", message}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(cmd.Environ(),
+			"GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME="+name, "GIT_COMMITTER_EMAIL="+email, "GIT_COMMITTER_DATE="+date)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v %s", args, err, out)
+		}
+	}
+}
+
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Skipf("git is not available: %v %s", err, out)
+	}
+	return repo
+}
+
+func TestReadGitMetadata(t *testing.T) {
+	repo := initTestRepo(t)
+	t.Setenv("GITHUB_SHA", "")
+	articleFile := filepath.Join(repo, "intro", "intro.md")
+	writeTestFile(t, articleFile, "First draft")
+	commitTestRepo(t, repo, "Ada", "ada@example.com", "2026-01-02T10:00:00Z", "Add intro")
+	writeTestFile(t, articleFile, "Second draft")
+	commitTestRepo(t, repo, "Grace", "grace@example.com", "2026-02-03T10:00:00Z", "Fix typos")
+	writeTestFile(t, articleFile, "Third draft")
+	commitTestRepo(t, repo, "Ada", "ADA@example.com", "2026-03-04T10:00:00Z", "Expand intro")
+
+	metadata, err := readGitMetadata(articleFile)
+	if err != nil {
+		t.Fatalf(`readGitMetadata returned unexpected error: %v`, err)
+	}
+	if !metadata.CreatedAt.Equal(time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)) || !metadata.UpdatedAt.Equal(time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf(`readGitMetadata dates = %v, %v`, metadata.CreatedAt, metadata.UpdatedAt)
+	}
+	if len(metadata.Authors) != 2 || metadata.Authors[0].Name != "Ada" || metadata.Authors[1].Name != "Grace" {
+		t.Fatalf(`readGitMetadata authors = %v, but need Ada and Grace`, metadata.Authors)
+	}
+	if metadata.LatestAuthor == nil || metadata.LatestAuthor.Email != "ADA@example.com" {
+		t.Fatalf(`readGitMetadata latest author = %v, but need the author of the last commit`, metadata.LatestAuthor)
+	}
+	if len(metadata.RevisionNote) != len("1234567: Expand intro") || metadata.RevisionNote[7:] != ": Expand intro" {
+		t.Fatalf(`readGitMetadata revision note = %q`, metadata.RevisionNote)
+	}
+
+	// A shallow clone does not know when the article was created
+	clone := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "-q", "--depth", "1", "file://"+repo, clone).CombinedOutput(); err != nil {
+		t.Fatalf("git clone failed: %v %s", err, out)
+	}
+	metadata, err = readGitMetadata(filepath.Join(clone, "intro", "intro.md"))
+	if err != nil || metadata.CreatedAt != nil || metadata.UpdatedAt == nil {
+		t.Fatalf(`readGitMetadata = %+v, %v, but need no created_at in a shallow clone`, metadata, err)
+	}
+}
+
+func TestInsideWorkTree(t *testing.T) {
+	repo := initTestRepo(t)
+	if !insideWorkTree(repo) || insideWorkTree(t.TempDir()) {
+		t.Fatalf(`insideWorkTree needs to be true only inside the repository`)
+	}
+}
+
+func TestGitMetadataPayloadFields(t *testing.T) {
+	t.Setenv("GIT_METADATA_FIELDS", "created_at=first_published, revision_note=changelog")
+	created := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	metadata := GitMetadata{CreatedAt: &created, RevisionNote: "abc1234: Add intro"}
+	fields, err := metadata.payloadFields()
+	if err != nil {
+		t.Fatalf(`payloadFields returned unexpected error: %v`, err)
+	}
+	data, err := json.Marshal(Article{Title: "Intro", Extra: fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]any
+	json.Unmarshal(data, &payload)
+	if payload["title"] != "Intro" || payload["first_published"] != "2026-01-02T10:00:00Z" || payload["changelog"] != "abc1234: Add intro" {
+		t.Fatalf(`unexpected article payload: %s`, data)
+	}
+	if _, ok := payload["updated_at"]; ok {
+		t.Fatalf(`payload should not contain missing git fields: %s`, data)
+	}
+
+	for _, invalid := range []string{"created=first_published", "created_at=title", "created_at=changes,revision_note=changes", "updated_at=created_at"} {
+		t.Setenv("GIT_METADATA_FIELDS", invalid)
+		if _, err := metadata.payloadFields(); err == nil {
+			t.Fatalf(`payloadFields(%q) expected an error`, invalid)
+		}
+	}
+}
+
+func TestGitMetadataAuthorEmails(t *testing.T) {
+	metadata := GitMetadata{Authors: []GitAuthor{{Name: "Ada", Email: "ada@example.com"}}}
+	for _, test := range []struct {
+		optIn string
+		want  string
+	}{{"", `[{"name":"Ada"}]`}, {"true", `[{"name":"Ada","email":"ada@example.com"}]`}} {
+		t.Setenv("GIT_AUTHOR_EMAILS", test.optIn)
+		fields, err := metadata.payloadFields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := json.Marshal(fields["authors"]); string(data) != test.want {
+			t.Fatalf(`authors with GIT_AUTHOR_EMAILS=%q = %s, but need %v`, test.optIn, data, test.want)
+		}
+	}
+}
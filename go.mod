@@ -2,4 +2,10 @@ module github.com/abhiramjoshi/article_uploader
 
 go 1.23.4
 
-require github.com/sethvargo/go-githubactions v1.3.0
+require (
+	github.com/sethvargo/go-githubactions v1.3.0
+	golang.org/x/image v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/text v0.23.0 // indirect
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// Actions taken for each image when an existing article is updated
+const (
+	imageNew       = "new"
+	imageChanged   = "changed"
+	imageUnchanged = "unchanged"
+	imageDeleted   = "deleted"
+	// Removed locally but left on the server, as deleting is opt-in
+	imageKept = "kept"
+)
+
+// An ImageChange is what happens to one image when an article is updated
+type ImageChange struct {
+	Filename string
+	Action   string
+}
+
+// Hex encoded SHA-256 of an image's contents
+func imageHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Hash of an image on the server, from its hash field or else its base64 data. Images whose
+// contents cannot be compared have an empty hash and are uploaded again.
+func serverImageHash(image Image) string {
+	if image.Hash != "" {
+		return image.Hash
+	}
+	if image.Data == nil {
+		return ""
+	}
+	data, err := b64.StdEncoding.DecodeString(*image.Data)
+	if err != nil {
+		return ""
+	}
+	return imageHash(data)
+}
+
+// Whether the alt text, caption or display order of an image differs from the server's
+func imageMetadataChanged(local Image, server Image) bool {
+	return local.Alt != server.Alt || local.Caption != server.Caption || local.Order != server.Order
+}
+
+// Compare the local images of an article with the images of the article on the server by
+// filename, content hash and metadata. Returns the images to upload and the action for every image.
+func diffImages(local []Image, server []Image) ([]Image, []ImageChange) {
+	existing := map[string]Image{}
+	for _, image := range server {
+		existing[image.Filename] = image
+	}
+	upload := []Image{}
+	var changes []ImageChange
+	for _, image := range local {
+		serverImage, ok := existing[image.Filename]
+		hash := serverImageHash(serverImage)
+		switch {
+		case !ok:
+			changes = append(changes, ImageChange{Filename: image.Filename, Action: imageNew})
+			upload = append(upload, image)
+		case hash == "" || hash != image.Hash || imageMetadataChanged(image, serverImage):
+			changes = append(changes, ImageChange{Filename: image.Filename, Action: imageChanged})
+			upload = append(upload, image)
+		default:
+			changes = append(changes, ImageChange{Filename: image.Filename, Action: imageUnchanged})
+		}
+	}
+	for _, image := range server {
+		if !slices.ContainsFunc(local, func(localImage Image) bool { return localImage.Filename == image.Filename }) {
+			changes = append(changes, ImageChange{Filename: image.Filename, Action: imageDeleted})
+		}
+	}
+	return upload, changes
+}
+
+// Keep images removed from the photos folder on the server when KEEP_IMAGES is true
+func keepRemovedImages(changes []ImageChange) []ImageChange {
+	if os.Getenv("KEEP_IMAGES") != "true" {
+		return changes
+	}
+	for i := range changes {
+		if changes[i].Action == imageDeleted {
+			changes[i].Action = imageKept
+		}
+	}
+	return changes
+}
+
+// Delete the images removed from the photos folder from the article on the server
+func deleteArticleImages(articleID int, changes []ImageChange) error {
+	endpoint, err := apiURL("ENDPOINT")
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Action != imageDeleted {
+			continue
+		}
+		imageURL := endpoint + fmt.Sprintf("%d/images/%v/", articleID, url.PathEscape(change.Filename))
+		if err := sendAuthorizedJSON(http.MethodDelete, imageURL, nil, nil); err != nil {
+			return fmt.Errorf("Error deleting image %v: %v", change.Filename, err)
+		}
+	}
+	return nil
+}
+
+// Report the action taken for each image of an updated article
+func reportImageChanges(title string, changes []ImageChange) {
+	for _, change := range changes {
+		logger.Info("Image synced", "article", title, "image", change.Filename, "action", change.Action)
+		fmt.Printf("Image %v of %v: %v\n", change.Filename, title, change.Action)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testImage(filename string, contents string) Image {
+	data := b64.StdEncoding.EncodeToString([]byte(contents))
+	return Image{Filename: filename, Data: &data, ContentType: "image/png", Hash: imageHash([]byte(contents))}
+}
+
+func TestDiffImages(t *testing.T) {
+	local := []Image{testImage("sunset", "new sunset"), testImage("harbour", "harbour"), testImage("beach", "beach"), testImage("alley", "alley")}
+	serverData := b64.StdEncoding.EncodeToString([]byte("beach"))
+	server := []Image{
+		{Filename: "sunset", Hash: imageHash([]byte("old sunset"))},
+		{Filename: "harbour", Hash: imageHash([]byte("harbour"))},
+		// Without a hash the server's data is compared, and without either the image is sent again
+		{Filename: "beach", Data: &serverData},
+		{Filename: "alley"},
+		{Filename: "removed", Hash: imageHash([]byte("removed"))},
+	}
+
+	upload, changes := diffImages(local, server)
+	var uploaded, actions []string
+	for _, image := range upload {
+		uploaded = append(uploaded, image.Filename)
+	}
+	for _, change := range changes {
+		actions = append(actions, change.Filename+" "+change.Action)
+	}
+	if strings.Join(uploaded, ", ") != "sunset, alley" {
+		t.Fatalf("diffImages uploads %v, but need [sunset alley]", uploaded)
+	}
+	want := "sunset changed, harbour unchanged, beach unchanged, alley changed, removed deleted"
+	if strings.Join(actions, ", ") != want {
+		t.Fatalf("diffImages actions = %v, but need %v", strings.Join(actions, ", "), want)
+	}
+
+	// Edited alt text, captions and order are sent again
+	captioned := testImage("harbour", "harbour")
+	captioned.Caption = "The harbour at dawn"
+	upload, changes = diffImages([]Image{captioned}, server[1:2])
+	if len(upload) != 1 || changes[0].Action != imageChanged {
+		t.Fatalf("diffImages = %v, %v, but need the recaptioned harbour to be sent", upload, changes)
+	}
+	upload, _ = diffImages(nil, server[1:2])
+	if data, err := json.Marshal(Article{Images: upload}); err != nil || !strings.Contains(string(data), `"images":[]`) {
+		t.Fatalf(`Article without changed images = %s, %v, but need "images":[]`, data, err)
+	}
+
+	_, changes = diffImages([]Image{testImage("cover", "cover")}, nil)
+	if len(changes) != 1 || changes[0].Action != imageNew {
+		t.Fatalf("diffImages actions = %v, but need cover to be new", changes)
+	}
+}
+
+func TestUploadArticleSyncsImages(t *testing.T) {
+	id := 7
+	existing := Article{ID: &id, Title: "Harbour walk", Images: []Image{
+		{Filename: "harbour", Hash: imageHash([]byte("harbour"))},
+		{Filename: "old photo", Hash: imageHash([]byte("old photo"))},
+	}}
+	var sent []string
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]Article{existing})
+		case http.MethodPatch:
+			var update struct{ Images []Image }
+			json.NewDecoder(r.Body).Decode(&update)
+			for _, image := range update.Images {
+				sent = append(sent, image.Filename)
+			}
+			json.NewEncoder(w).Encode(existing)
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.EscapedPath())
+		default:
+			t.Errorf("Unexpected %v request to %v", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("GET_ENDPOINT", "api/articles/")
+	t.Setenv("ENDPOINT", "api/articles/")
+
+	article := Article{Title: "Harbour walk", Images: []Image{testImage("harbour", "harbour"), testImage("sunset", "sunset")}}
+	if _, err := uploadArticle(article); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(sent, ", ") != "sunset" {
+		t.Fatalf("uploadArticle sent images %v, but need only the new sunset", sent)
+	}
+	if strings.Join(deleted, ", ") != "/api/articles/7/images/old%20photo/" {
+		t.Fatalf("uploadArticle deleted %v, but need the removed old photo", deleted)
+	}
+
+	// Removed images are kept on the server when KEEP_IMAGES is set
+	deleted = nil
+	t.Setenv("KEEP_IMAGES", "true")
+	if _, err := uploadArticle(article); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("uploadArticle deleted %v, but need nothing deleted with KEEP_IMAGES", deleted)
+	}
+	t.Setenv("KEEP_IMAGES", "")
+
+	// Translations share the primary article's photos, so nothing is deleted from them
+	deleted, sent = nil, nil
+	article.TranslationOf = &id
+	if _, err := uploadArticle(article); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("uploadArticle deleted %v from a translation, but need nothing deleted", deleted)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// An ArticleLink is a link from an article's content to another article in the repository
+type ArticleLink struct {
+	File   string
+	Line   int
+	Target string
+	// Url the link was rewritten to from ARTICLE_URL
+	URL     string
+	Article IndexedArticle
+}
+
+// Whether a link target points at a file or folder in the repository rather than a url or anchor
+func isRelativeLink(target string) bool {
+	if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return false
+	}
+	return !strings.Contains(target, "://") && !strings.HasPrefix(target, "mailto:")
+}
+
+// Find the indexed article a path is part of
+func indexedArticleAt(index []IndexedArticle, path string) (IndexedArticle, bool) {
+	for _, article := range index {
+		folder, err := filepath.Abs(article.Folder)
+		if err != nil {
+			continue
+		}
+		if path == folder || path == article.File || filepath.Dir(path) == folder {
+			return article, true
+		}
+	}
+	return IndexedArticle{}, false
+}
+
+// Rewrite relative links to other articles' markdown files or folders to their public urls.
+// Links to markdown files, or to folders under the articles root, that are not articles are
+// reported as errors.
+func rewriteArticleLinks(content string, articleFolder string, root string, index []IndexedArticle) (string, []ArticleLink, []Diagnostic) {
+	var links []ArticleLink
+	var diagnostics []Diagnostic
+	folder, err := filepath.Abs(articleFolder)
+	if err != nil {
+		return content, nil, nil
+	}
+
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] || !strings.Contains(line, "](") {
+			continue
+		}
+		lines[i] = markdownLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+			parts := markdownLinkPattern.FindStringSubmatch(link)
+			if strings.HasPrefix(parts[1], "!") || !isRelativeLink(parts[2]) {
+				return link
+			}
+			target, fragment, _ := strings.Cut(parts[2], "#")
+			if unescaped, err := url.PathUnescape(target); err == nil {
+				target = unescaped
+			}
+			path := filepath.Join(folder, filepath.FromSlash(target))
+			info, statErr := os.Stat(path)
+			isMarkdown := filepath.Ext(path) == ".md"
+			isFolder := statErr == nil && info.IsDir()
+			if !isMarkdown && !isFolder {
+				// Photos, attachments and other files are not articles
+				return link
+			}
+			linked, ok := indexedArticleAt(index, path)
+			if statErr != nil || !ok {
+				if isMarkdown || strings.HasPrefix(path, root+string(filepath.Separator)) {
+					diagnostics = append(diagnostics, Diagnostic{Severity: severityError, Line: i + 1,
+						Message: fmt.Sprintf("Link to %v does not point at an article", parts[2])})
+				}
+				return link
+			}
+			if linkedFolder, _ := filepath.Abs(linked.Folder); linkedFolder == folder {
+				// Links within the article only keep their anchor
+				if fragment == "" {
+					return link
+				}
+				return parts[1] + "#" + fragment + parts[3]
+			}
+			address, err := articleURL(linked.Slug)
+			if err != nil {
+				diagnostics = append(diagnostics, Diagnostic{Severity: severityError, Line: i + 1, Message: err.Error()})
+				return link
+			}
+			links = append(links, ArticleLink{Line: i + 1, Target: parts[2], URL: address, Article: linked})
+			if fragment != "" {
+				address += "#" + fragment
+			}
+			return parts[1] + address + parts[3]
+		})
+	}
+	return strings.Join(lines, "\n"), links, diagnostics
+}
+
+// Check that every linked article has been published on the server, filling in the id and url the
+// server reports for it. Links to articles that are missing from the server or still drafts are reported as errors.
+func checkLinkedArticles(links []ArticleLink, serverArticles []Article) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i, link := range links {
+		linked := Article{Language: link.Article.Language, DefaultLanguage: link.Article.Language}
+		var published *Article
+		for j := range serverArticles {
+			if serverArticles[j].Title == link.Article.Title && sameLanguage(linked, serverArticles[j]) {
+				published = &serverArticles[j]
+				break
+			}
+		}
+		if published == nil {
+			diagnostics = append(diagnostics, Diagnostic{Severity: severityError, File: link.File, Line: link.Line,
+				Message: fmt.Sprintf("Linked article %q has not been uploaded yet", link.Article.Title)})
+			continue
+		}
+		if published.Status == statusDraft {
+			diagnostics = append(diagnostics, Diagnostic{Severity: severityError, File: link.File, Line: link.Line,
+				Message: fmt.Sprintf("Linked article %q is an unpublished draft", link.Article.Title)})
+			continue
+		}
+		links[i].Article.ID = published.ID
+		links[i].Article.URL = published.URL
+	}
+	return diagnostics
+}
+
+// Point links at the urls the server reports for the linked articles, where they differ from ARTICLE_URL
+func useServerURLs(content string, links []ArticleLink) string {
+	for _, link := range links {
+		if link.Article.URL == "" || link.URL == "" || link.Article.URL == link.URL {
+			continue
+		}
+		for _, end := range []string{")", "#", " "} {
+			content = strings.ReplaceAll(content, "]("+link.URL+end, "]("+link.Article.URL+end)
+		}
+	}
+	return content
+}
+
+// Check the published state of every article linked from an article against the server, and
+// link to the published articles' server urls
+func checkArticleLinks(article *Article, articleFile string) ([]Diagnostic, error) {
+	if len(article.Links) == 0 {
+		return nil, nil
+	}
+	serverArticles, err := fetchArticles()
+	if err != nil {
+		return nil, err
+	}
+	// Links carry the file and line they were written on
+	diagnostics := checkLinkedArticles(article.Links, serverArticles)
+	for i := range diagnostics {
+		if diagnostics[i].File == "" {
+			diagnostics[i].File = articleFile
+		}
+	}
+	article.Content = useServerURLs(article.Content, article.Links)
+	return diagnostics, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRewriteArticleLinks(t *testing.T) {
+	t.Setenv("ARTICLE_URL", "https://example.com/articles/{slug}/")
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "intro_to_go", "intro_to_go.md"), "Intro")
+	writeTestFile(t, filepath.Join(root, "part_two", "index.md"), "Part two")
+	writeTestFile(t, filepath.Join(root, "part_two", "photos", "gopher.png"), "png")
+	writeTestFile(t, filepath.Join(root, "notes", "todo.txt"), "not an article")
+	writeTestFile(t, filepath.Join(root, "my post", "my post.md"), "---\nslug: my-post\n---\nMine")
+	index, err := buildArticleIndex(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := strings.Join([]string{
+		"See [the intro](../intro_to_go/intro_to_go.md) and [its setup](../intro_to_go#setup).",
+		"Back to [the top](index.md#part-two), ![a gopher](photos/gopher.png) and [Go](https://go.dev).",
+		"```",
+		"[not rewritten](../intro_to_go)",
+		"```",
+		"[broken](../missing.md) [not an article](../notes)",
+		"[mine](../my%20post/my%20post.md)",
+	}, "\n")
+	want := strings.Join([]string{
+		"See [the intro](https://example.com/articles/intro-to-go/) and [its setup](https://example.com/articles/intro-to-go/#setup).",
+		"Back to [the top](#part-two), ![a gopher](photos/gopher.png) and [Go](https://go.dev).",
+		"```",
+		"[not rewritten](../intro_to_go)",
+		"```",
+		"[broken](../missing.md) [not an article](../notes)",
+		"[mine](https://example.com/articles/my-post/)",
+	}, "\n")
+
+	got, links, diagnostics := rewriteArticleLinks(content, filepath.Join(root, "part_two"), root, index)
+	if got != want {
+		t.Fatalf("rewriteArticleLinks =\n%v\nbut need\n%v", got, want)
+	}
+	if len(links) != 3 || links[0].Article.Slug != "intro-to-go" || links[1].Target != "../intro_to_go#setup" || links[1].URL != "https://example.com/articles/intro-to-go/" {
+		t.Fatalf(`rewriteArticleLinks links = %+v, but need the two links to intro_to_go and the one to my post`, links)
+	}
+	wantDiagnostics := []Diagnostic{
+		{Severity: severityError, Line: 6, Message: "Link to ../missing.md does not point at an article"},
+		{Severity: severityError, Line: 6, Message: "Link to ../notes does not point at an article"},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf(`rewriteArticleLinks diagnostics = %v, but need %v`, diagnostics, wantDiagnostics)
+	}
+}
+
+func TestCheckLinkedArticles(t *testing.T) {
+	t.Setenv("ARTICLE_URL", "https://example.com/{slug}")
+	links := []ArticleLink{
+		{Line: 3, URL: "https://example.com/published", Article: IndexedArticle{Title: "Published", Slug: "published", Language: "en"}},
+		{Line: 5, Article: IndexedArticle{Title: "Draft", Slug: "draft", Language: "en"}},
+		{Line: 9, Article: IndexedArticle{Title: "Missing", Slug: "missing", Language: "en"}},
+	}
+	publishedID := 12
+	serverArticles := []Article{
+		{Title: "Published", Language: "de", Status: statusPublished, URL: "https://example.com/de/published/"},
+		{ID: &publishedID, Title: "Published", Status: statusPublished, URL: "https://example.com/blog/published/"},
+		{Title: "Draft", Status: statusDraft},
+	}
+
+	diagnostics := checkLinkedArticles(links, serverArticles)
+	wantDiagnostics := []Diagnostic{
+		{Severity: severityError, Line: 5, Message: `Linked article "Draft" is an unpublished draft`},
+		{Severity: severityError, Line: 9, Message: `Linked article "Missing" has not been uploaded yet`},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf(`checkLinkedArticles diagnostics = %v, but need %v`, diagnostics, wantDiagnostics)
+	}
+	if links[0].Article.URL != "https://example.com/blog/published/" || links[0].Article.ID == nil || *links[0].Article.ID != publishedID {
+		t.Fatalf(`checkLinkedArticles did not fill in the published article's id and url, got %+v`, links[0].Article)
+	}
+	if links[1].Article.ID != nil || links[2].Article.ID != nil {
+		t.Fatalf(`checkLinkedArticles filled in the id of an unpublished article, got %+v`, links[1:])
+	}
+
+	// Links use the url the server reports
+	content := "[a](https://example.com/published) [b](https://example.com/published#setup) [c](https://example.com/published-two)"
+	want := "[a](https://example.com/blog/published/) [b](https://example.com/blog/published/#setup) [c](https://example.com/published-two)"
+	if got := useServerURLs(content, links); got != want {
+		t.Fatalf(`useServerURLs = %q, but need %q`, got, want)
+	}
+}
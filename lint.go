@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const lintConfigFile = "lint.yaml"
+
+// Rules with severity off are not run
+const severityOff = "off"
+
+// Built in lint rules
+const (
+	lintMaxTitleLength      = "max-title-length"
+	lintRequiredFrontMatter = "required-front-matter"
+	lintBannedPhrases       = "banned-phrases"
+	lintMaxImages           = "max-images"
+	lintImageAltText        = "image-alt-text"
+	lintNoBareURLs          = "no-bare-urls"
+	lintHeadingCase         = "heading-case"
+)
+
+// A LintRule configures one of the built in rules. Only the options a rule uses are read.
+type LintRule struct {
+	Severity string `yaml:"severity"`
+	// max-title-length and max-images
+	Max int `yaml:"max"`
+	// required-front-matter
+	Keys []string `yaml:"keys"`
+	// banned-phrases
+	Phrases []string `yaml:"phrases"`
+	// heading-case: sentence or title, with words such as product names that keep their case
+	Style string   `yaml:"style"`
+	Words []string `yaml:"words"`
+}
+
+// A CustomLintRule reports every line of content matching a regular expression
+type CustomLintRule struct {
+	Name     string `yaml:"name"`
+	Pattern  string `yaml:"pattern"`
+	Message  string `yaml:"message"`
+	Severity string `yaml:"severity"`
+	pattern  *regexp.Regexp
+}
+
+// LintConfig is read from lint.yaml at the repository root
+type LintConfig struct {
+	Rules  map[string]LintRule `yaml:"rules"`
+	Custom []CustomLintRule    `yaml:"custom"`
+	// Thresholds of the readability check, which runs with defaults without lint.yaml
+	Readability ReadabilityConfig `yaml:"readability"`
+}
+
+// Load the lint rules, returning nil when the repository does not have any
+func loadLintConfig(root string) (*LintConfig, error) {
+	data, err := os.ReadFile(filepath.Join(root, lintConfigFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", lintConfigFile, err)
+	}
+	var config LintConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Error parsing %v: %v", lintConfigFile, err)
+	}
+	known := []string{lintMaxTitleLength, lintRequiredFrontMatter, lintBannedPhrases, lintMaxImages, lintImageAltText, lintNoBareURLs, lintHeadingCase}
+	for name, rule := range config.Rules {
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("Unknown rule %v in %v, expected one of %v", name, lintConfigFile, strings.Join(known, ", "))
+		}
+		if err := validateSeverity(name, rule.Severity); err != nil {
+			return nil, err
+		}
+		if name == lintHeadingCase && rule.Style != "sentence" && rule.Style != "title" {
+			return nil, fmt.Errorf("Rule %v in %v needs a style of sentence or title", name, lintConfigFile)
+		}
+		if (name == lintMaxTitleLength || name == lintMaxImages) && rule.Severity != severityOff && rule.Max <= 0 {
+			return nil, fmt.Errorf("Rule %v in %v needs a max greater than 0", name, lintConfigFile)
+		}
+	}
+	if severity := config.Readability.Severity; severity != "" {
+		if err := validateSeverity("readability", severity); err != nil {
+			return nil, err
+		}
+	}
+	for i, rule := range config.Custom {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("Custom rules in %v need a name and a pattern", lintConfigFile)
+		}
+		if err := validateSeverity(rule.Name, rule.Severity); err != nil {
+			return nil, err
+		}
+		if config.Custom[i].pattern, err = regexp.Compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("Invalid pattern for rule %v in %v: %v", rule.Name, lintConfigFile, err)
+		}
+	}
+	return &config, nil
+}
+
+func validateSeverity(name string, severity string) error {
+	switch severity {
+	case severityWarning, severityError, severityOff:
+		return nil
+	}
+	return fmt.Errorf("Rule %v in %v has severity %q, expected error, warning or off", name, lintConfigFile, severity)
+}
+
+// Markdown images with their alt text
+var imageReferencePattern = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+
+var bareURLPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+// Words kept in lower case in title case headings, unless they start or end the heading
+var titleCaseMinorWords = []string{"a", "an", "and", "as", "at", "but", "by", "for", "in", "nor", "of", "on", "or", "the", "to", "vs", "with"}
+
+// Whether a heading follows sentence or title case. Words with digits, acronyms and the
+// configured words are accepted as written.
+func followsHeadingCase(text string, style string, keep []string) bool {
+	words := strings.Fields(text)
+	for i, word := range words {
+		trimmed := strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if trimmed == "" || slices.Contains(keep, trimmed) || strings.ContainsFunc(trimmed, unicode.IsDigit) || strings.ToUpper(trimmed) == trimmed {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(trimmed)
+		upper := unicode.IsUpper(first)
+		switch {
+		case i == 0:
+			if !upper {
+				return false
+			}
+		case style == "sentence":
+			if upper {
+				return false
+			}
+		case style == "title":
+			minor := slices.Contains(titleCaseMinorWords, strings.ToLower(trimmed)) && i != len(words)-1
+			if upper == minor {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Run the configured lint rules over a prepared article. fields are the article's raw front
+// matter. Lines of content problems are relative to the content.
+func (config LintConfig) lint(article Article, fields map[string]any) []Diagnostic {
+	// Images are always checked for alt text, as a warning unless lint.yaml says otherwise
+	if _, ok := config.Rules[lintImageAltText]; !ok {
+		config.Rules = maps.Clone(config.Rules)
+		if config.Rules == nil {
+			config.Rules = map[string]LintRule{}
+		}
+		config.Rules[lintImageAltText] = LintRule{Severity: severityWarning}
+	}
+	// Alt text can also come from captions.yaml or the images front matter
+	describedImages := map[string]bool{}
+	for _, image := range article.Images {
+		describedImages[image.Filename] = strings.TrimSpace(image.Alt) != ""
+	}
+	var diagnostics []Diagnostic
+	report := func(name string, line int, format string, args ...any) {
+		rule := config.Rules[name]
+		diagnostics = append(diagnostics, Diagnostic{Severity: rule.Severity, Line: line, Message: name + ": " + fmt.Sprintf(format, args...)})
+	}
+	enabled := func(name string) bool {
+		rule, ok := config.Rules[name]
+		return ok && rule.Severity != severityOff
+	}
+
+	if rule := config.Rules[lintMaxTitleLength]; enabled(lintMaxTitleLength) && len([]rune(article.Title)) > rule.Max {
+		report(lintMaxTitleLength, 0, "Title is %d characters long, the maximum is %d", len([]rune(article.Title)), rule.Max)
+	}
+	if enabled(lintRequiredFrontMatter) {
+		for _, key := range config.Rules[lintRequiredFrontMatter].Keys {
+			if _, ok := fields[key]; !ok {
+				report(lintRequiredFrontMatter, 0, "Front matter is missing %v", key)
+			}
+		}
+	}
+	if enabled(lintHeadingCase) {
+		rule := config.Rules[lintHeadingCase]
+		for _, heading := range article.TOC {
+			if !followsHeadingCase(heading.Text, rule.Style, rule.Words) {
+				report(lintHeadingCase, heading.Line, "Heading %q is not in %v case", heading.Text, rule.Style)
+			}
+		}
+	}
+
+	var bannedPhrases []*regexp.Regexp
+	if enabled(lintBannedPhrases) {
+		for _, phrase := range config.Rules[lintBannedPhrases].Phrases {
+			bannedPhrases = append(bannedPhrases, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(phrase)+`\b`))
+		}
+	}
+	lines, inCode := markdownLines(article.Content)
+	images := 0
+	for i, line := range lines {
+		if inCode[i] {
+			continue
+		}
+		// Inline code is not prose, but keep its width so reported text stays in place
+		prose := inlineCodePattern.ReplaceAllStringFunc(line, func(code string) string {
+			return strings.Repeat(" ", len(code))
+		})
+		for _, image := range imageReferencePattern.FindAllStringSubmatch(prose, -1) {
+			images++
+			if enabled(lintImageAltText) && strings.TrimSpace(image[1]) == "" && !describedImages[photoKey(image[2])] {
+				report(lintImageAltText, i+1, "Image %v has no alt text", image[2])
+			}
+		}
+		for j, pattern := range bannedPhrases {
+			if pattern.MatchString(prose) {
+				report(lintBannedPhrases, i+1, "Avoid %q", config.Rules[lintBannedPhrases].Phrases[j])
+			}
+		}
+		if enabled(lintNoBareURLs) {
+			for _, match := range bareURLPattern.FindAllStringIndex(prose, -1) {
+				if match[0] > 0 && strings.ContainsRune(`(<"'[=`, rune(prose[match[0]-1])) {
+					continue
+				}
+				report(lintNoBareURLs, i+1, "Bare url %v, write it as a link", prose[match[0]:match[1]])
+			}
+		}
+		for _, rule := range config.Custom {
+			if rule.Severity != severityOff && rule.pattern.MatchString(prose) {
+				message := rule.Message
+				if message == "" {
+					message = fmt.Sprintf("Matches %v", rule.Pattern)
+				}
+				diagnostics = append(diagnostics, Diagnostic{Severity: rule.Severity, Line: i + 1, Message: rule.Name + ": " + message})
+			}
+		}
+	}
+	if rule := config.Rules[lintMaxImages]; enabled(lintMaxImages) && images > rule.Max {
+		report(lintMaxImages, 0, "Article has %d images, the maximum is %d", images, rule.Max)
+	}
+	return diagnostics
+}
+
+// Lint an article with the repository's lint.yaml, or only run the alt text and readability
+// checks with their defaults when it does not have one. Fills in the article's readability.
+func lintArticle(article *Article, articleFile string, source string, sourceMap *SourceMap) ([]Diagnostic, error) {
+	config, err := loadLintConfig(repoRoot(filepath.Dir(articleFile)))
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &LintConfig{}
+	}
+	fields, err := frontMatterFields(source)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics := config.lint(*article, fields)
+	article.Readability = measureReadability(article.Content, config.Readability.maxSentenceWords())
+	diagnostics = append(diagnostics, config.Readability.check(article.Readability)...)
+	for i := range article.Readability.LongSentences {
+		article.Readability.LongSentences[i].Line = sourceMap.source(article.Readability.LongSentences[i].Line).Line
+	}
+	return sourceMap.locate(diagnostics, articleFile), nil
+}
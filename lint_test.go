@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	githubactions "github.com/sethvargo/go-githubactions"
+)
+
+const testLintConfig = `rules:
+  max-title-length: {severity: warning, max: 20}
+  required-front-matter: {severity: error, keys: [title, tags]}
+  banned-phrases: {severity: warning, phrases: [simply, "just works"]}
+  max-images: {severity: error, max: 1}
+  image-alt-text: {severity: error}
+  no-bare-urls: {severity: warning}
+  heading-case: {severity: warning, style: sentence, words: [Go]}
+custom:
+  - name: no-todo
+    pattern: "TODO"
+    message: Resolve the TODO before publishing
+    severity: error
+`
+
+func TestLoadLintConfig(t *testing.T) {
+	root := t.TempDir()
+	if config, err := loadLintConfig(root); config != nil || err != nil {
+		t.Fatalf(`loadLintConfig without lint.yaml = %v %v, but need nil`, config, err)
+	}
+	for _, invalid := range []string{
+		"rules:\n  max-words: {severity: error}\n",
+		"rules:\n  no-bare-urls: {severity: fatal}\n",
+		"rules:\n  heading-case: {severity: error, style: camel}\n",
+		"rules:\n  max-images: {severity: error}\n",
+		"rules:\n  max-title-length: {severity: warning, max: 0}\n",
+		"custom:\n  - {name: broken, pattern: '(', severity: error}\n",
+	} {
+		writeTestFile(t, filepath.Join(root, lintConfigFile), invalid)
+		if _, err := loadLintConfig(root); err == nil {
+			t.Fatalf("loadLintConfig(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, lintConfigFile), testLintConfig)
+	config, err := loadLintConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join([]string{
+		"# Getting Started With Go",
+		"It simply works, see https://go.dev or [the docs](https://go.dev/doc/).",
+		"![](photo) ![A gopher](gopher)",
+		"`https://example.com` TODO",
+		"```",
+		"TODO: https://example.com",
+		"```",
+		"## Why Go is fast",
+	}, "\n")
+	article := Article{Title: "A title that is far too long", Content: content, TOC: extractHeadings(content)}
+
+	diagnostics := config.lint(article, map[string]any{"title": "x"})
+	want := []Diagnostic{
+		{Severity: severityWarning, Message: "max-title-length: Title is 28 characters long, the maximum is 20"},
+		{Severity: severityError, Message: "required-front-matter: Front matter is missing tags"},
+		{Severity: severityWarning, Line: 1, Message: `heading-case: Heading "Getting Started With Go" is not in sentence case`},
+		{Severity: severityWarning, Line: 2, Message: `banned-phrases: Avoid "simply"`},
+		{Severity: severityWarning, Line: 2, Message: "no-bare-urls: Bare url https://go.dev, write it as a link"},
+		{Severity: severityError, Line: 3, Message: "image-alt-text: Image photo has no alt text"},
+		{Severity: severityError, Line: 4, Message: "no-todo: Resolve the TODO before publishing"},
+		{Severity: severityError, Message: "max-images: Article has 2 images, the maximum is 1"},
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Fatalf("lint =\n%v\nbut need\n%v", diagnostics, want)
+	}
+}
+
+func TestFollowsHeadingCase(t *testing.T) {
+	tests := []struct {
+		text  string
+		style string
+		want  bool
+	}{
+		{"Getting started with the API", "sentence", true},
+		{"Getting Started", "sentence", false},
+		{"Upgrading to v2", "sentence", true},
+		{"Getting Started with the API", "title", true},
+		{"Getting started", "title", false},
+		{"What It Is For", "title", true},
+		{"Notes On the Release", "title", false},
+	}
+	for _, test := range tests {
+		if got := followsHeadingCase(test.text, test.style, nil); got != test.want {
+			t.Fatalf(`followsHeadingCase(%q, %v) = %v, but need %v`, test.text, test.style, got, test.want)
+		}
+	}
+}
+
+func TestSummarizeDiagnostics(t *testing.T) {
+	summaryFile := filepath.Join(t.TempDir(), "summary.md")
+	writeTestFile(t, summaryFile, "")
+	action := githubactions.New(githubactions.WithGetenv(func(name string) string {
+		if name == "GITHUB_STEP_SUMMARY" {
+			return summaryFile
+		}
+		return ""
+	}))
+
+	summarizeDiagnostics(action, "My Article", []Diagnostic{
+		{Severity: severityError, File: "a/a.md", Line: 3, Message: "no-todo: Resolve a | b"},
+		{Severity: severityWarning, File: "a/a.md", Message: "Title too long"},
+	})
+	data, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "### My Article\n\n| Severity | Location | Problem |\n| --- | --- | --- |\n| error | `a/a.md:3` | no-todo: Resolve a \\| b |\n| warning | `a/a.md` | Title too long |\n"
+	if !strings.Contains(string(data), want) {
+		t.Fatalf("summary =\n%v\nbut need\n%v", string(data), want)
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	b64 "encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -10,7 +9,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	githubactions "github.com/sethvargo/go-githubactions"
 )
@@ -18,17 +19,73 @@ import (
 var logger *slog.Logger
 
 type Image struct {
-	Filename string  `json:"filename"`
-	Data     *string `json:"data"`
-	Filepath string  `json:"-"`
+	Filename    string  `json:"filename"`
+	Data        *string `json:"data"`
+	ContentType string  `json:"content_type"`
+	Alt         string  `json:"alt,omitempty"`
+	Caption     string  `json:"caption,omitempty"`
+	Order       int     `json:"order"`
+	Width       int     `json:"width,omitempty"`
+	Height      int     `json:"height,omitempty"`
+	Hash        string  `json:"hash,omitempty"`
+	Filepath    string  `json:"-"`
 }
 
 type Article struct {
-	ID      *int    `json:"id,omitempty"`
-	Title   string  `json:"title"`
-	Path    string  `json:"-"`
-	Images  []Image `json:"images"`
-	Content string  `json:"content"`
+	ID          *int         `json:"id,omitempty"`
+	Title       string       `json:"title"`
+	Path        string       `json:"-"`
+	Images      []Image      `json:"images"`
+	Attachments []Attachment `json:"-"`
+	Content     string       `json:"content"`
+	URL         string       `json:"url,omitempty"`
+	Language    string       `json:"language,omitempty"`
+	// Language of articles without one, from the article folder's config
+	DefaultLanguage string        `json:"-"`
+	TranslationOf   *int          `json:"translation_of,omitempty"`
+	Status          string        `json:"status,omitempty"`
+	PublishAt       *time.Time    `json:"publish_at,omitempty"`
+	AuthorID        *int          `json:"author_id,omitempty"`
+	Tags            []string      `json:"-"`
+	Category        string        `json:"-"`
+	TagIDs          *[]int        `json:"tag_ids,omitempty"`
+	CategoryID      *int          `json:"category_id,omitempty"`
+	WordCount       int           `json:"word_count,omitempty"`
+	ReadingTime     int           `json:"reading_time,omitempty"`
+	Excerpt         string        `json:"excerpt,omitempty"`
+	TOC             []Heading     `json:"toc,omitempty"`
+	CoverImage      string        `json:"cover_image,omitempty"`
+	Diagnostics     []Diagnostic  `json:"-"`
+	Links           []ArticleLink `json:"-"`
+	Readability     Readability   `json:"-"`
+	Git             GitMetadata   `json:"-"`
+	// Optional payload fields whose names are only known at runtime
+	Extra map[string]any `json:"-"`
+}
+
+// Articles are sent with their Extra fields merged into the payload
+func (article Article) MarshalJSON() ([]byte, error) {
+	type articleFields Article
+	if article.Images == nil {
+		// An article without images is sent with an empty list rather than null
+		article.Images = []Image{}
+	}
+	data, err := json.Marshal(articleFields(article))
+	if err != nil || len(article.Extra) == 0 {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range article.Extra {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("Error encoding payload field %v: %v", name, err)
+		}
+		fields[name] = raw
+	}
+	return json.Marshal(fields)
 }
 
 func init() {
@@ -50,68 +107,244 @@ func init() {
 }
 
 func main() {
+	action := githubactions.New()
+	command := action.GetInput("command")
+	if os.Getenv("PLATFORM") != "GITHUB" && len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	switch command {
+	case "", "upload":
+		uploadFolder(action)
+	case "publish-due":
+		published, err := publishDueArticles(time.Now())
+		if err != nil {
+			logger.Error("There was an error publishing scheduled articles", "error", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Published %d scheduled articles\n", len(published))
+	case "sync-authors":
+		cwd, err := os.Getwd()
+		if err != nil {
+			logger.Error("There was an error finding the working directory", "error", err)
+			os.Exit(1)
+		}
+		root := repoRoot(cwd)
+		registry, err := loadAuthorRegistry(root)
+		if err == nil && registry == nil {
+			err = fmt.Errorf("No %v found at the repository root", authorRegistryFile)
+		}
+		if err == nil {
+			err = syncAuthors(root, registry)
+		}
+		if err != nil {
+			logger.Error("There was an error syncing authors", "error", err)
+			os.Exit(1)
+		}
+	default:
+		logger.Error("Unknown command", "command", command)
+		os.Exit(1)
+	}
+}
+
+// Upload the article folder given as the action input
+func uploadFolder(action *githubactions.Action) {
+	// Article folders are indexed and dictionaries loaded once for every article and translation of the run
+	articleIndexes = map[string][]IndexedArticle{}
+	loadedDictionaries = map[[2]string]*Dictionary{}
 	// Get article folder
 	var folder string
-	action := githubactions.New()
 	if os.Getenv("PLATFORM") != "GITHUB" {
 		folder = "test"
 	} else {
 		folder = action.GetInput("article_folder")
 		logger.Debug(fmt.Sprintf("Getting input from Github Input: %v", folder))
+		if folder == "" {
+			logger.Error("The article_folder input is required to upload an article")
+			os.Exit(1)
+		}
 	}
 	// Parse article folder to create article payload
 	articleName, articleFilepath, articlePhotos, err := parseArticle(folder)
 	if err != nil {
 		logger.Error("There was an error parsing the article folder", "error", err)
 		os.Exit(1)
-  }
-  article, err := createArticlePayload(articleName, articleFilepath, articlePhotos)
-  if err != nil {
-    logger.Error("There was an error creating the article payload", "error", err)
-    os.Exit(1)
-  }
-  if os.Getenv("DRYRUN") == "true" {
-    logger.Debug("Not sending POST request in dry run")
-    os.Exit(0)
-  }
-  // Check if article exists
-  existArticle, err := checkIfArticleExists(article)
-  if err != nil {
-    logger.Error("There was an error checking if article exists", "error", err)
-    os.Exit(1)
-  }
-
-  // If exists, send put request
-  var response *http.Response
-  if existArticle != nil {
-    logger.Debug("Article exists, so sending PATCH")
+	}
+	articleFiles := map[string]string{articleName: articleFilepath}
+	articleNames := []string{articleName}
+	subArticles, err := findSubArticles(folder, articleFilepath)
+	if err != nil {
+		logger.Error("There was an error finding sub-articles", "error", err)
+		os.Exit(1)
+	}
+	for _, subArticle := range subArticles {
+		name := strings.TrimSuffix(filepath.Base(subArticle), ".md")
+		articleFiles[name] = subArticle
+		articleNames = append(articleNames, name)
+	}
+
+	translations, err := findTranslations(folder, articleFilepath)
+	if err != nil {
+		logger.Error("There was an error finding article translations", "error", err)
+		os.Exit(1)
+	}
+
+	for _, name := range articleNames {
+		article, err := createArticlePayload(name, articleFiles[name], articlePhotos)
+		if err != nil {
+			logger.Error("There was an error creating the article payload", "error", err, "article", name)
+			os.Exit(1)
+		}
+		reportDiagnostics(action, article.Diagnostics)
+		summarizeDiagnostics(action, article.Title, article.Diagnostics)
+		summarizeReadability(action, article.Title, article.Readability)
+		if hasErrors(article.Diagnostics) {
+			logger.Error("Article failed validation and will not be uploaded", "article", name)
+			os.Exit(1)
+		}
+		dryRun := os.Getenv("DRYRUN") == "true"
+		var uploaded *Article
+		if dryRun {
+			logger.Debug("Not sending POST request in dry run", "article", name)
+			if len(article.Links) > 0 {
+				logger.Warn("Linked articles are not checked against the server in a dry run", "article", name)
+			}
+		} else {
+			linkDiagnostics, err := checkArticleLinks(&article, articleFiles[name])
+			if err != nil {
+				logger.Error("There was an error checking linked articles", "error", err, "article", name)
+				os.Exit(1)
+			}
+			reportDiagnostics(action, linkDiagnostics)
+			if hasErrors(linkDiagnostics) {
+				logger.Error("Article links to articles that are not published", "article", name)
+				os.Exit(1)
+			}
+			uploaded, err = uploadArticle(article)
+			if err != nil {
+				logger.Error("There was an error uploading the article", "error", err, "article", name)
+				os.Exit(1)
+			}
+		}
+		if name != articleName || len(translations) == 0 {
+			continue
+		}
+		// Translations share the primary article's photos, which have already been uploaded
+		if !dryRun && uploaded.ID == nil {
+			logger.Error("Could not determine the id of the uploaded article to link its translations to", "article", name)
+			os.Exit(1)
+		}
+		for _, translationFile := range translations {
+			translation, err := createTranslationPayload(name, translationFile, articlePhotos, article)
+			if err != nil {
+				logger.Error("There was an error creating the translation payload", "error", err, "translation", translationFile)
+				os.Exit(1)
+			}
+			reportDiagnostics(action, translation.Diagnostics)
+			summarizeDiagnostics(action, translation.Title, translation.Diagnostics)
+			summarizeReadability(action, translation.Title, translation.Readability)
+			if hasErrors(translation.Diagnostics) {
+				logger.Error("Translation failed validation and will not be uploaded", "translation", translationFile)
+				os.Exit(1)
+			}
+			if dryRun {
+				logger.Debug("Not sending POST request in dry run", "translation", translationFile)
+				if len(translation.Links) > 0 {
+					logger.Warn("Linked articles are not checked against the server in a dry run", "translation", translationFile)
+				}
+				continue
+			}
+			translation.TranslationOf = uploaded.ID
+			linkDiagnostics, err := checkArticleLinks(&translation, translationFile)
+			if err != nil {
+				logger.Error("There was an error checking linked articles", "error", err, "translation", translationFile)
+				os.Exit(1)
+			}
+			reportDiagnostics(action, linkDiagnostics)
+			if hasErrors(linkDiagnostics) {
+				logger.Error("Translation links to articles that are not published", "translation", translationFile)
+				os.Exit(1)
+			}
+			if _, err := uploadArticle(translation); err != nil {
+				logger.Error("There was an error uploading the translation", "error", err, "translation", translationFile)
+				os.Exit(1)
+			}
+		}
+	}
+	logger.Debug(fmt.Sprintf("%v, %v, %v, %v", folder, articleFilepath, articleName, articlePhotos))
+}
+
+// Upload an article, creating it if it does not exist on the server yet and updating it otherwise.
+// Returns the article as stored by the server.
+func uploadArticle(article Article) (*Article, error) {
+	// Tags and categories are resolved before anything is uploaded, so a missing term stops the upload
+	if err := resolveArticleTaxonomy(&article); err != nil {
+		return nil, err
+	}
+	// Attachments are uploaded next so the article links to their hosted urls
+	if len(article.Attachments) > 0 {
+		attachmentUrls, err := uploadAttachments(article.Attachments)
+		if err != nil {
+			return nil, fmt.Errorf("There was an error uploading the article attachments: %v", err)
+		}
+		article.Content = rewriteAttachmentLinks(article.Content, attachmentUrls)
+	}
+	// Check if article exists
+	existArticle, err := checkIfArticleExists(article)
+	if err != nil {
+		return nil, fmt.Errorf("There was an error checking if article exists: %v", err)
+	}
+
+	// If exists, send put request
+	var response *http.Response
+	var imageChanges []ImageChange
+	if existArticle != nil {
+		logger.Debug("Article exists, so sending PATCH")
+		// Only new and changed images are sent. Translations share the primary article's
+		// photos, so images are never deleted from them.
+		allImages := article.Images
+		article.Images, imageChanges = diffImages(article.Images, existArticle.Images)
+		if article.TranslationOf != nil {
+			imageChanges = slices.DeleteFunc(imageChanges, func(change ImageChange) bool { return change.Action == imageDeleted })
+		}
+		imageChanges = keepRemovedImages(imageChanges)
 		response, err = sendPutRequest(article, *existArticle.ID)
+		article.Images = allImages
 	} else {
-    logger.Debug("Article does not exist, so sending POST")
+		logger.Debug("Article does not exist, so sending POST")
 		response, err = sendPostRequest(article)
 	}
 	if err != nil {
-		logger.Error("There was an error sending the post request", "error", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("There was an error sending the post request: %v", err)
 	}
 	// If not exists, send post request
 	defer response.Body.Close()
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		logger.Error("Error reading response body", "error", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("Error reading response body: %v", err)
 	}
-	logger.Info("Recieved response", "status", *&response.Status, "body", string(body))
+	logger.Info("Recieved response", "status", response.Status, "body", string(body))
 	fmt.Printf("Recieved response: status: %v body: %v", response.Status, string(body))
-	logger.Debug(fmt.Sprintf("%v, %v, %v, %v", folder, articleFilepath, articleName, articlePhotos))
+	if existArticle != nil && response.StatusCode >= 200 && response.StatusCode <= 299 {
+		if err := deleteArticleImages(*existArticle.ID, imageChanges); err != nil {
+			return nil, err
+		}
+		reportImageChanges(article.Title, imageChanges)
+	}
+
+	var uploaded Article
+	if err := json.Unmarshal(body, &uploaded); err != nil || uploaded.ID == nil {
+		logger.Debug("Response did not contain the uploaded article", "error", err)
+		uploaded = article
+		if existArticle != nil {
+			uploaded.ID = existArticle.ID
+		}
+	}
+	return &uploaded, nil
 }
 
 func checkIfImage(imageData []byte) bool {
-	mimeType := http.DetectContentType(imageData)
-	logger.Info(fmt.Sprintf("Image is of type: %v", mimeType))
-	imageMimes := []string{"image/jpeg", "image/png", "image/gif"}
-	for _, imageType := range imageMimes {
-		if mimeType == imageType {
+	for _, media := range supportedMediaTypes() {
+		if media.Detect(imageData) {
 			return true
 		}
 	}
@@ -137,20 +370,35 @@ func parseArticle(articleFolder string) (string, string, string, error) {
 		return "", "", "", err
 	}
 
-	var articleFile string = ""
-	var articleName string = ""
+	config, err := loadFolderConfig(articleFolder)
+	if err != nil {
+		return "", "", "", err
+	}
 
+	var markdownFiles []string
+	var notebookFiles []string
 	for _, file := range folderFiles {
 		logger.Debug(fmt.Sprintf("Considering file %v in article folder", file.Name()))
 		logger.Debug(fmt.Sprintf("File extension is: %v", filepath.Ext(file.Name())))
-		if filepath.Ext(file.Name()) == ".md" {
-			articleFile = file.Name()
-			articleName = strings.TrimSuffix(file.Name(), ".md")
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".md" {
+			markdownFiles = append(markdownFiles, file.Name())
 		}
+		if !file.IsDir() && filepath.Ext(file.Name()) == notebookExtension {
+			notebookFiles = append(notebookFiles, file.Name())
+		}
+	}
+	// Notebooks are articles in folders without markdown, or when named as the main file
+	if len(markdownFiles) == 0 || filepath.Ext(config.Main) == notebookExtension {
+		markdownFiles = append(markdownFiles, notebookFiles...)
 	}
 
-	if articleFile == "" {
-		return "", "", "", fmt.Errorf("Folder must contain an article written as a `.md` file")
+	articleFile, err := selectMainFile(articleFolder, markdownFiles, config)
+	if err != nil {
+		return "", "", "", err
+	}
+	articleName, err := markdownName(articleFolder, articleFile)
+	if err != nil {
+		return "", "", "", err
 	}
 
 	articleFilepath := filepath.Join(articleFolder, articleFile)
@@ -163,32 +411,18 @@ func parseArticle(articleFolder string) (string, string, string, error) {
 	return articleName, articleFilepath, articlePhotos, nil
 }
 
-func checkIfArticleExists(article Article) (*Article, error) {
-	// Send get request to check if article exists
-	base_url, exists := os.LookupEnv("BASE_DOMAIN")
-	if !exists {
-		return nil, fmt.Errorf("Base url does not exists, please set the BASE_DOMAIN env variable")
-	}
-	get_endpoint, exists := os.LookupEnv("GET_ENDPOINT")
-	if !exists {
-		return nil, fmt.Errorf("Endpoint not provided, please set the ENDPOINT env variable")
-	}
-
-	env := os.Getenv("ENV")
-	if len(env) == 0 {
-		env = "PROD"
-	}
-	protocol := "https://"
-	if env == "DEV" {
-		protocol = "http://"
+// Fetch every article currently stored on the server
+func fetchArticles() ([]Article, error) {
+	url, err := apiURL("GET_ENDPOINT")
+	if err != nil {
+		return nil, err
 	}
-
-	url := protocol + base_url + "/" + get_endpoint
 	resp, err := http.Get(url)
 	if err != nil {
 		logger.Error("There was an error requesting articles")
 		return nil, err
 	}
+	defer resp.Body.Close()
 	response, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logger.Error("There was an issue reading the response body")
@@ -197,118 +431,228 @@ func checkIfArticleExists(article Article) (*Article, error) {
 
 	var articles []Article
 	json.Unmarshal(response, &articles)
-  //logger.Debug("Returned articles", "articles", fmt.Sprintf("%v", articles))
+	return articles, nil
+}
+
+func checkIfArticleExists(article Article) (*Article, error) {
+	// Send get request to check if article exists
+	articles, err := fetchArticles()
+	if err != nil {
+		return nil, err
+	}
+	//logger.Debug("Returned articles", "articles", fmt.Sprintf("%v", articles))
 	for i := 0; i < len(articles); i++ {
 		logger.Debug("Checking articles for matches", "article1", article, "article2", articles[i])
-    if article.Title == articles[i].Title {
+		if article.Title == articles[i].Title && sameLanguage(article, articles[i]) {
 			logger.Debug("Article titles matches", article.Title, articles[i].Title)
-      return &articles[i], nil
+			return &articles[i], nil
 		}
 	}
-  logger.Debug("There were no matching articles")
+	logger.Debug("There were no matching articles")
 	return nil, nil
 }
 
 func sendPutRequest(article Article, id int) (*http.Response, error) {
-  // Put request logic
-	base_url, exists := os.LookupEnv("BASE_DOMAIN")
-	if !exists {
-		return nil, fmt.Errorf("Base url does not exists, please set the BASE_DOMAIN env variable")
-	}
-	post_endpoint, exists := os.LookupEnv("ENDPOINT")
-	if !exists {
-		return nil, fmt.Errorf("Endpoint not provided, please set the ENDPOINT env variable")
-	}
-
-	env := os.Getenv("ENV")
-	if len(env) == 0 {
-		env = "PROD"
-	}
-	protocol := "https://"
-	if env == "DEV" {
-		protocol = "http://"
-	}
-
-  url := protocol + base_url + "/" + post_endpoint + fmt.Sprintf("%d/", id)
-	logger.Debug(fmt.Sprintf("Sending request to: %v", url))
-	// Need to handle authentication, this can be just simple authentication in our case.
-	user := os.Getenv("USERNAME")
-	pass := os.Getenv("PASSWORD")
-	auth := user + ":" + pass
-	basicAuth := b64.StdEncoding.EncodeToString([]byte(auth))
-
-	body, err := json.Marshal(article)
+	// Put request logic
+	endpoint, err := apiURL("ENDPOINT")
 	if err != nil {
-		return nil, fmt.Errorf("There was an error formatting the post body: %v", err)
+		return nil, err
 	}
-	//logger.Debug(fmt.Sprintf("Article post request: %v", string(body)))
-	// send post request
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
-	req.Header.Add("Authorization", "Basic "+basicAuth)
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	url := endpoint + fmt.Sprintf("%d/", id)
+	resp, err := sendAuthorizedRequest(http.MethodPatch, url, article)
 	if err != nil {
 		return nil, fmt.Errorf("Error sending article update request: %v", err)
 	}
-
 	return resp, nil
 }
+
 // Send POST request cont. article payload to site
 func sendPostRequest(article Article) (*http.Response, error) {
-	base_url, exists := os.LookupEnv("BASE_DOMAIN")
-	if !exists {
-		return nil, fmt.Errorf("Base url does not exists, please set the BASE_DOMAIN env variable")
-	}
-	post_endpoint, exists := os.LookupEnv("ENDPOINT")
-	if !exists {
-		return nil, fmt.Errorf("Endpoint not provided, please set the ENDPOINT env variable")
-	}
-
-	env := os.Getenv("ENV")
-	if len(env) == 0 {
-		env = "PROD"
-	}
-	protocol := "https://"
-	if env == "DEV" {
-		protocol = "http://"
-	}
-
-	url := protocol + base_url + "/" + post_endpoint
-	logger.Debug(fmt.Sprintf("Sending request to: %v", url))
-	// Need to handle authentication, this can be just simple authentication in our case.
-	user := os.Getenv("USERNAME")
-	pass := os.Getenv("PASSWORD")
-	auth := user + ":" + pass
-	basicAuth := b64.StdEncoding.EncodeToString([]byte(auth))
-
-	body, err := json.Marshal(article)
+	url, err := apiURL("ENDPOINT")
 	if err != nil {
-		return nil, fmt.Errorf("There was an error formatting the post body: %v", err)
+		return nil, err
 	}
-	//logger.Debug(fmt.Sprintf("Article post request: %v", string(body)))
-	// send post request
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
-	req.Header.Add("Authorization", "Basic "+basicAuth)
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := sendAuthorizedRequest(http.MethodPost, url, article)
 	if err != nil {
 		return nil, fmt.Errorf("Error sending article creation request: %v", err)
 	}
-
 	return resp, nil
 }
 
 // Creates an article object that can be sent via a POST requests
 func createArticlePayload(articleName string, articleFile string, articlePhotos string) (Article, error) {
+	return buildArticlePayload(articleName, articleFile, articlePhotos, nil)
+}
+
+// Creates the payload of a translation of the primary article. Translations read the primary
+// article's photos folder for embeds and galleries, but share its uploaded images and cover.
+func createTranslationPayload(articleName string, translationFile string, articlePhotos string, primary Article) (Article, error) {
+	return buildArticlePayload(articleName, translationFile, articlePhotos, &primary)
+}
+
+func buildArticlePayload(articleName string, articleFile string, articlePhotos string, primary *Article) (Article, error) {
 	data, err := os.ReadFile(articleFile)
 	if err != nil {
 		return Article{}, fmt.Errorf("Error reading file: %v", err)
 	}
 	title := strings.ReplaceAll(articleName, "_", " ")
 
+	var notebookImages []Image
+	if filepath.Ext(articleFile) == notebookExtension {
+		var markdown string
+		markdown, notebookImages, err = convertNotebook(data)
+		if err != nil {
+			return Article{}, fmt.Errorf("Error converting notebook %v: %v", articleFile, err)
+		}
+		data = []byte(markdown)
+	}
+
+	frontMatter, body, err := parseFrontMatter(string(data))
+	if err != nil {
+		return Article{}, fmt.Errorf("Error reading front matter of %v: %v", articleFile, err)
+	}
 	//content := strings.ReplaceAll(string(data), "\r\n", " ")
 	//content = strings.ReplaceAll(content, "\n", " ")
-	content := strings.TrimSpace(string(data))
+	content := strings.TrimSpace(body)
+	// Problems in the content are reported on the line of the file they were written on
+	sourceMap := newSourceMap(content, articleFile, bodyLine(string(data), body))
+
+	includes, err := findIncludedMarkdown(filepath.Dir(articleFile), articleFile)
+	if err != nil {
+		return Article{}, err
+	}
+	for _, include := range includes {
+		includeData, err := os.ReadFile(include)
+		if err != nil {
+			return Article{}, fmt.Errorf("Error reading included file: %v", err)
+		}
+		_, includeBody, err := parseFrontMatter(string(includeData))
+		if err != nil {
+			return Article{}, fmt.Errorf("Error reading front matter of %v: %v", include, err)
+		}
+		logger.Debug(fmt.Sprintf("Including %v in article %v", include, articleName))
+		content += "\n\n" + strings.TrimSpace(includeBody)
+		sourceMap.include(content, include, bodyLine(string(includeData), includeBody), strings.TrimSpace(includeBody))
+	}
+
+	imageMetadata, err := loadImageMetadata(articlePhotos, frontMatter)
+	if err != nil {
+		return Article{}, err
+	}
+	var diagnostics []Diagnostic
+	if strings.Contains(content, "{{<") {
+		var origins []int
+		var shortcodeDiagnostics []Diagnostic
+		content, origins, shortcodeDiagnostics = expandArticleShortcodes(content, articleFile, articlePhotos, imageMetadata)
+		diagnostics = append(diagnostics, sourceMap.locate(shortcodeDiagnostics, articleFile)...)
+		sourceMap.follow(origins)
+	}
+	if strings.Contains(content, "{{") {
+		var origins []int
+		var variableDiagnostics []Diagnostic
+		content, origins, variableDiagnostics, err = substituteArticleVariables(content, articleFile, frontMatter)
+		if err != nil {
+			return Article{}, err
+		}
+		diagnostics = append(diagnostics, sourceMap.locate(variableDiagnostics, articleFile)...)
+		sourceMap.follow(origins)
+	}
+	spellingDiagnostics, err := spellcheckArticle(content, articleFile)
+	if err != nil {
+		return Article{}, err
+	}
+	diagnostics = append(diagnostics, sourceMap.locate(spellingDiagnostics, articleFile)...)
+	if strings.Contains(content, "[!") {
+		var origins []int
+		content, origins, err = convertAlerts(content, repoRoot(filepath.Dir(articleFile)))
+		if err != nil {
+			return Article{}, fmt.Errorf("Error converting alerts in %v: %v", articleFile, err)
+		}
+		sourceMap.follow(origins)
+	}
+	bibliography, err := loadBibliography(filepath.Dir(articleFile))
+	if err != nil {
+		return Article{}, err
+	}
+	// Citations without a references.bib are reported by renderCitations
+	if bibliography != nil || strings.Contains(content, "[@") {
+		style, err := citationStyle(frontMatter)
+		if err != nil {
+			return Article{}, err
+		}
+		var citationDiagnostics []Diagnostic
+		content, citationDiagnostics = renderCitations(content, bibliography, style)
+		diagnostics = append(diagnostics, sourceMap.locate(citationDiagnostics, articleFile)...)
+		// Citations are rendered within their lines and the references are appended
+		sourceMap.extend(content)
+	}
+	var links []ArticleLink
+	if strings.Contains(content, "[[") || strings.Contains(content, "](") {
+		root := articlesRoot(filepath.Dir(articleFile))
+		index, err := articleIndex(root)
+		if err != nil {
+			return Article{}, err
+		}
+		var wikiLinks, relativeLinks []ArticleLink
+		var wikiDiagnostics, linkDiagnostics []Diagnostic
+		// Links are rewritten within their lines, so the source map still holds
+		content, wikiLinks, wikiDiagnostics = convertWikiLinks(content, photoNames(articlePhotos), index)
+		diagnostics = append(diagnostics, sourceMap.locate(wikiDiagnostics, articleFile)...)
+		content, relativeLinks, linkDiagnostics = rewriteArticleLinks(content, filepath.Dir(articleFile), root, index)
+		diagnostics = append(diagnostics, sourceMap.locate(linkDiagnostics, articleFile)...)
+		for _, link := range append(wikiLinks, relativeLinks...) {
+			source := sourceMap.source(link.Line)
+			link.File, link.Line = source.File, source.Line
+			links = append(links, link)
+		}
+	}
+	if frontMatter.Title != "" {
+		title = frontMatter.Title
+	}
+	config, err := loadFolderConfig(filepath.Dir(articleFile))
+	if err != nil {
+		return Article{}, err
+	}
+	status, publishAt, err := publishingState(frontMatter, config, time.Now())
+	if err != nil {
+		return Article{}, fmt.Errorf("Error reading publishing state of %v: %v", articleFile, err)
+	}
+	var gitMetadata GitMetadata
+	if !insideWorkTree(filepath.Dir(articleFile)) {
+		logger.Debug("Article is not in a git repository, git metadata will not be sent", "article", articleFile)
+	} else if gitMetadata, err = readGitMetadata(articleFile); err != nil {
+		logger.Warn("Could not read git history for article, git metadata will not be sent", "article", articleFile, "error", err)
+	}
+	extra, err := gitMetadata.payloadFields()
+	if err != nil {
+		return Article{}, err
+	}
+	authorID, err := resolveAuthorID(articleFile, frontMatter, gitMetadata)
+	if err != nil {
+		return Article{}, fmt.Errorf("Error resolving the author of %v: %v", articleFile, err)
+	}
+	if err := validateTaxonomy(articleFile, frontMatter); err != nil {
+		return Article{}, fmt.Errorf("Invalid tags or category in %v: %v", articleFile, err)
+	}
+	// Front matter values take precedence over the computed summary
+	wordCount := countWords(stripMarkdown(content))
+	if frontMatter.WordCount != nil {
+		wordCount = *frontMatter.WordCount
+	}
+	minutes := readingTime(wordCount)
+	if frontMatter.ReadingTime != nil {
+		minutes = *frontMatter.ReadingTime
+	}
+	excerpt := frontMatter.Excerpt
+	if excerpt == "" {
+		excerpt = generateExcerpt(content)
+	}
+	headings := extractHeadings(content)
+	diagnostics = append(diagnostics, sourceMap.locate(validateHeadings(headings), articleFile)...)
+	language := frontMatter.Lang
+	if _, suffix := languageSuffix(filepath.Dir(articleFile), articleFile); suffix != "" {
+		language = suffix
+	}
 
 	//Parse images
 	imageFiles, err := os.ReadDir(articlePhotos)
@@ -321,11 +665,13 @@ func createArticlePayload(articleName string, articleFile string, articlePhotos
 	var images []Image
 	var attachedImages []string
 	for _, image := range imageFiles {
+		if image.Name() == captionsFile {
+			continue
+		}
 		logger.Debug(fmt.Sprintf("Create image paycload for image %v", image))
 		imagePayload, err := createImagePayload(filepath.Join(articlePhotos, image.Name()))
-		// Over here, there is a potential to send nil data images and upload them later.
 		if err != nil {
-			logger.Debug(fmt.Sprintf("There was an error creating payload for image, skipping. Error: %v", err))
+			logger.Warn("Skipping file that could not be uploaded as an image", "image", image.Name(), "error", err)
 			continue
 		}
 		//ext := filepath.Ext(image.Name())
@@ -334,9 +680,78 @@ func createArticlePayload(articleName string, articleFile string, articlePhotos
 		attachedImages = append(attachedImages, imagePayload.Filename)
 		images = append(images, imagePayload)
 	}
+	for _, image := range notebookImages {
+		attachedImages = append(attachedImages, image.Filename)
+		images = append(images, image)
+	}
 	logger.Debug(fmt.Sprintf("Images to be sent are: %v", attachedImages))
+	alts := imageAltTexts(content)
+	for i := range images {
+		images[i].Alt = alts[images[i].Filename]
+	}
+	arrangeImages(images, imageMetadata)
+
+	cover, err := findCoverImage(frontMatter, images)
+	if err != nil {
+		return Article{}, err
+	}
+	if cover == "" && primary != nil {
+		cover = primary.CoverImage
+	} else if cover == "" && os.Getenv("GENERATE_COVER") != "false" {
+		logger.Debug(fmt.Sprintf("No cover image for %v, generating one", articleName))
+		generated, err := generateCoverImage(title, repoRoot(filepath.Dir(articleFile)))
+		if err != nil {
+			return Article{}, err
+		}
+		generated.Alt = title
+		generated.Order = len(images) + 1
+		images = append(images, generated)
+		cover = generated.Filename
+	}
+
+	attachments, err := collectAttachments(filepath.Dir(articleFile), frontMatter)
+	if err != nil {
+		return Article{}, err
+	}
+	secretDiagnostics, err := scanArticleSecrets(content, sourceMap, articleFile, attachments)
+	if err != nil {
+		return Article{}, err
+	}
+	diagnostics = append(diagnostics, secretDiagnostics...)
+	article := Article{
+		Title:           title,
+		Content:         content,
+		Images:          images,
+		Attachments:     attachments,
+		Language:        language,
+		DefaultLanguage: defaultLanguage(config),
+		Status:          status,
+		PublishAt:       publishAt,
+		AuthorID:        authorID,
+		Tags:            frontMatter.Tags,
+		Category:        frontMatter.Category,
+		WordCount:       wordCount,
+		ReadingTime:     minutes,
+		Excerpt:         excerpt,
+		TOC:             headings,
+		CoverImage:      cover,
+		Diagnostics:     diagnostics,
+		Links:           links,
+		Git:             gitMetadata,
+		Extra:           extra,
+		Path:            filepath.Dir(articleFile),
+	}
+	lintDiagnostics, err := lintArticle(&article, articleFile, string(data), sourceMap)
+	if err != nil {
+		return Article{}, err
+	}
+	article.Diagnostics = append(article.Diagnostics, lintDiagnostics...)
+	if primary != nil {
+		// The photos were uploaded with the primary article
+		article.Images = nil
+	}
 	logger.Debug(fmt.Sprintf("Successfully created article payload for %v", articleName))
-	return Article{Title: title, Content: content, Images: images, Path: filepath.Dir(articleFile)}, nil
+	return article, nil
 }
 
 func createImagePayload(imageFile string) (Image, error) {
@@ -355,58 +770,30 @@ func createImagePayload(imageFile string) (Image, error) {
 		if os.IsNotExist(err) {
 			return Image{}, fmt.Errorf("Error: Image %v does not exist", imageFile)
 		}
-		return Image{Filename: imageName, Data: nil}, nil
+		return Image{}, fmt.Errorf("Error reading image %v: %v", imageFile, err)
 	}
-	logger.Debug("Checking if image is a valid image file")
-	if !checkIfImage(raw_data) {
-		logger.Info(fmt.Sprintf("Provided image file: %v is not a valid image, image will be added with dummy data", imageFile))
-		return Image{Filename: imageName, Data: nil}, nil
+	logger.Debug("Checking if image is a supported media file")
+	contentType, err := detectMediaType(imageFile, raw_data)
+	if err != nil {
+		return Image{}, err
+	}
+	if contentType == "image/svg+xml" {
+		raw_data, err = sanitizeSVG(raw_data)
+		if err != nil {
+			return Image{}, fmt.Errorf("Error sanitizing image %v: %v", imageFile, err)
+		}
 	}
-	logger.Debug("Image is valid")
+	logger.Debug(fmt.Sprintf("Image is valid with content type %v", contentType))
+	width, height := imageDimensions(contentType, raw_data)
 	data := b64.StdEncoding.EncodeToString(raw_data)
-	return Image{Filename: imageName, Data: &data}, nil
+	return Image{Filename: imageName, Data: &data, ContentType: contentType, Width: width, Height: height, Hash: imageHash(raw_data), Filepath: imageFile}, nil
 }
 
 func sendImageUpdate(url string, image Image) (*http.Response, error) {
-	//base_url, exists := os.LookupEnv("BASE_DOMAIN")
-	//if !exists {
-	//	return nil, fmt.Errorf("Base url does not exists, please set the BASE_DOMAIN env variable")
-	//}
-	//post_endpoint, exists := os.LookupEnv("ENDPOINT")
-	//if !exists {
-	//	return nil, fmt.Errorf("Endpoint not provided, please set the ENDPOINT env variable")
-	//}
-
-	//env := os.Getenv("ENV")
-	//if len(env) == 0 {
-	//	env = "PROD"
-	//}
-	//protocol := "https://"
-	//if env == "DEV" {
-	//	protocol = "http://"
-	//}
-
-	logger.Debug(fmt.Sprintf("Sending request to: %v", url))
-	// Need to handle authentication, this can be just simple authentication in our case.
-	user := os.Getenv("USERNAME")
-	pass := os.Getenv("PASSWORD")
-	auth := user + ":" + pass
-	basicAuth := b64.StdEncoding.EncodeToString([]byte(auth))
-
-	body, err := json.Marshal(image)
-	if err != nil {
-		return nil, fmt.Errorf("There was an error formatting the post body: %v", err)
-	}
-	//logger.Debug(fmt.Sprintf("Article post request: %v", string(body)))
-	// send post request
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
-	req.Header.Add("Authorization", "Basic "+basicAuth)
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := sendAuthorizedRequest(http.MethodPatch, url, image)
 	if err != nil {
-		return nil, fmt.Errorf("Error sending article creation request: %v", err)
+		return nil, fmt.Errorf("Error sending image update request: %v", err)
 	}
-
 	return resp, nil
 }
 
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// A mediaType describes a file type that can be uploaded alongside an article.
+// Files are matched by extension and then confirmed by looking at their magic bytes.
+type mediaType struct {
+	Name        string
+	ContentType string
+	Extensions  []string
+	Detect      func(data []byte) bool
+}
+
+var mediaTypes = []mediaType{
+	{Name: "jpeg", ContentType: "image/jpeg", Extensions: []string{".jpg", ".jpeg"}, Detect: sniffs("image/jpeg")},
+	{Name: "png", ContentType: "image/png", Extensions: []string{".png"}, Detect: sniffs("image/png")},
+	{Name: "gif", ContentType: "image/gif", Extensions: []string{".gif"}, Detect: sniffs("image/gif")},
+	{Name: "webp", ContentType: "image/webp", Extensions: []string{".webp"}, Detect: sniffs("image/webp")},
+	{Name: "svg", ContentType: "image/svg+xml", Extensions: []string{".svg"}, Detect: isSVG},
+	{Name: "avif", ContentType: "image/avif", Extensions: []string{".avif"}, Detect: isAVIF},
+	{Name: "ico", ContentType: "image/x-icon", Extensions: []string{".ico"}, Detect: sniffs("image/x-icon")},
+}
+
+func sniffs(contentType string) func(data []byte) bool {
+	return func(data []byte) bool {
+		return http.DetectContentType(data) == contentType
+	}
+}
+
+func isSVG(data []byte) bool {
+	// The XML declaration, comments and a doctype of any length may come before the first element
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return false
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			return strings.EqualFold(token.Name.Local, "svg")
+		case xml.CharData:
+			if len(bytes.TrimSpace(token)) > 0 {
+				return false
+			}
+		}
+	}
+}
+
+// AVIF files are ISO-BMFF containers with an `avif` or `avis` brand in the ftyp box
+func isAVIF(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	brand := string(data[8:12])
+	return brand == "avif" || brand == "avis"
+}
+
+// Media types can be restricted with the MEDIA_TYPES env variable or the media_types input,
+// e.g. "jpeg,png,svg". When neither is set every known media type is supported.
+func supportedMediaTypes() []mediaType {
+	configured := os.Getenv("MEDIA_TYPES")
+	if configured == "" {
+		configured = os.Getenv("INPUT_MEDIA_TYPES")
+	}
+	if len(strings.TrimSpace(configured)) == 0 {
+		return mediaTypes
+	}
+	var supported []mediaType
+	for _, name := range strings.Split(configured, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, media := range mediaTypes {
+			if media.Name == name {
+				supported = append(supported, media)
+			}
+		}
+	}
+	return supported
+}
+
+// Determine the content type of a media file from its extension and contents
+func detectMediaType(filename string, data []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	supported := supportedMediaTypes()
+	for _, media := range supported {
+		for _, mediaExt := range media.Extensions {
+			if ext != mediaExt {
+				continue
+			}
+			if !media.Detect(data) {
+				return "", fmt.Errorf("File %v has a %v extension but its contents are not %v", filename, ext, media.ContentType)
+			}
+			return media.ContentType, nil
+		}
+	}
+	// Fall back to the file contents for files without a recognised extension
+	for _, media := range supported {
+		if media.Name != "svg" && media.Detect(data) {
+			return media.ContentType, nil
+		}
+	}
+	return "", fmt.Errorf("File %v is not a supported media type (detected %v)", filename, http.DetectContentType(data))
+}
+
+// SVG elements kept by the sanitizer. Everything else, including script, foreignObject and
+// elements of other namespaces such as editor metadata, is removed with its content.
+var svgAllowedElements = []string{
+	"a", "animate", "animateMotion", "animateTransform", "circle", "clipPath", "defs", "desc", "ellipse",
+	"feBlend", "feColorMatrix", "feComponentTransfer", "feComposite", "feConvolveMatrix", "feDiffuseLighting",
+	"feDisplacementMap", "feDistantLight", "feDropShadow", "feFlood", "feFuncA", "feFuncB", "feFuncG", "feFuncR",
+	"feGaussianBlur", "feImage", "feMerge", "feMergeNode", "feMorphology", "feOffset", "fePointLight",
+	"feSpecularLighting", "feSpotLight", "feTile", "feTurbulence", "filter", "g", "image", "line",
+	"linearGradient", "marker", "mask", "path", "pattern", "polygon", "polyline", "radialGradient", "rect",
+	"set", "stop", "style", "svg", "switch", "symbol", "text", "textPath", "title", "tspan", "use", "view",
+}
+
+// Attribute namespace prefixes kept by the sanitizer
+var svgAllowedPrefixes = []string{"", "xlink", "xml", "xmlns"}
+
+var svgTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+var svgAttributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func svgName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// Lower cased value without whitespace or control characters, which browsers ignore in urls
+func normalizeSVGValue(value string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value))
+}
+
+// Links may only point at a fragment in the same file or at an http(s) url
+func safeSVGLink(value string) bool {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "#") {
+		return true
+	}
+	link, err := url.Parse(value)
+	return err == nil && (link.Scheme == "http" || link.Scheme == "https")
+}
+
+func allowedSVGAttribute(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	value := normalizeSVGValue(attr.Value)
+	switch {
+	case !slices.Contains(svgAllowedPrefixes, attr.Name.Space), strings.HasPrefix(local, "on"):
+		return false
+	case local == "href":
+		return safeSVGLink(attr.Value)
+	}
+	return !strings.Contains(value, "javascript:") && !strings.Contains(value, "vbscript:")
+}
+
+func allowedSVGElement(element xml.StartElement) bool {
+	if (element.Name.Space != "" && element.Name.Space != "svg") || !slices.Contains(svgAllowedElements, element.Name.Local) {
+		return false
+	}
+	// Animations must not change links or event handlers
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "attributeName" {
+			target := strings.ToLower(attr.Value[strings.LastIndex(attr.Value, ":")+1:])
+			if target == "href" || strings.HasPrefix(target, "on") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Rebuild an SVG from an allowlist of elements and attributes so it is safe to serve inline.
+// Scripts, event handlers, animations of links and links other than http(s) urls and fragments
+// are removed, and SVGs that are not well formed XML are rejected.
+func sanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var sanitized bytes.Buffer
+	var open []xml.Name
+	// Depth of the removed element the decoder is in, if any
+	removed := 0
+	// Whether the last start tag written is still open, so an empty element can be closed with />
+	pending := false
+	// Text of the style element the decoder is in, which is checked as a whole once it closes
+	// since CDATA sections and comments can split it
+	var style *strings.Builder
+	closePending := func() {
+		if pending {
+			sanitized.WriteString(">")
+			pending = false
+		}
+	}
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid SVG: %v", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			open = append(open, token.Name)
+			// Elements inside a style element are removed along with their text
+			if removed > 0 || style != nil || !allowedSVGElement(token) {
+				removed++
+				continue
+			}
+			closePending()
+			sanitized.WriteString("<" + svgName(token.Name))
+			for _, attr := range token.Attr {
+				if allowedSVGAttribute(attr) {
+					fmt.Fprintf(&sanitized, ` %v="%v"`, svgName(attr.Name), svgAttributeEscaper.Replace(attr.Value))
+				}
+			}
+			pending = true
+			if token.Name.Local == "style" {
+				style = &strings.Builder{}
+			}
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != token.Name {
+				return nil, fmt.Errorf("Invalid SVG: unexpected closing tag %v", svgName(token.Name))
+			}
+			open = open[:len(open)-1]
+			if removed > 0 {
+				removed--
+				continue
+			}
+			if style != nil {
+				text := style.String()
+				style = nil
+				if css := normalizeSVGValue(text); text != "" && !strings.Contains(css, "javascript:") && !strings.Contains(css, "@import") && !strings.Contains(css, "expression(") {
+					closePending()
+					sanitized.WriteString(svgTextEscaper.Replace(text))
+				}
+			}
+			if pending {
+				sanitized.WriteString("/>")
+				pending = false
+				continue
+			}
+			sanitized.WriteString("</" + svgName(token.Name) + ">")
+		case xml.CharData:
+			if removed > 0 {
+				continue
+			}
+			if style != nil {
+				style.Write(token)
+				continue
+			}
+			closePending()
+			sanitized.WriteString(svgTextEscaper.Replace(string(token)))
+		case xml.ProcInst:
+			if token.Target == "xml" && len(open) == 0 {
+				fmt.Fprintf(&sanitized, "<?xml %s?>", token.Inst)
+			}
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("Invalid SVG: %v is not closed", svgName(open[len(open)-1]))
+	}
+	return sanitized.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	pngHeader  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	webpHeader = []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")
+	avifHeader = []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00")
+	icoHeader  = []byte("\x00\x00\x01\x00\x01\x00\x10\x10")
+)
+
+func TestDetectMediaType(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     []byte
+		want     string
+	}{
+		{"photo.png", pngHeader, "image/png"},
+		{"photo.webp", webpHeader, "image/webp"},
+		{"photo.avif", avifHeader, "image/avif"},
+		{"favicon.ico", icoHeader, "image/x-icon"},
+		{"diagram.svg", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`), "image/svg+xml"},
+		// A long comment before the svg element
+		{"commented.svg", []byte(`<?xml version="1.0"?><!-- ` + strings.Repeat("licence ", 200) + ` --><!DOCTYPE svg><svg></svg>`), "image/svg+xml"},
+		{"noextension", webpHeader, "image/webp"},
+	}
+	for _, test := range tests {
+		got, err := detectMediaType(test.filename, test.data)
+		if err != nil || got != test.want {
+			t.Errorf(`detectMediaType(%q) = %q, %v, but need %q`, test.filename, got, err, test.want)
+		}
+	}
+}
+
+func TestDetectMediaTypeUnsupported(t *testing.T) {
+	if got, err := detectMediaType("notes.txt", []byte("just some text")); err == nil {
+		t.Fatalf(`detectMediaType("notes.txt") = %q, expected an error`, got)
+	}
+	if got, err := detectMediaType("page.svg", []byte(`<html><body><svg></svg></body></html>`)); err == nil {
+		t.Fatalf(`detectMediaType("page.svg") = %q, expected an error for a document that is not an svg`, got)
+	}
+	if got, err := detectMediaType("fake.png", webpHeader); err == nil {
+		t.Fatalf(`detectMediaType("fake.png") = %q, expected an error for mismatched contents`, got)
+	}
+}
+
+func TestDetectMediaTypeConfigured(t *testing.T) {
+	t.Setenv("MEDIA_TYPES", "jpeg, png")
+	if got, err := detectMediaType("photo.webp", webpHeader); err == nil {
+		t.Fatalf(`detectMediaType("photo.webp") = %q, expected webp to be disabled`, got)
+	}
+	if _, err := detectMediaType("photo.png", pngHeader); err != nil {
+		t.Fatalf(`detectMediaType("photo.png") returned unexpected error: %v`, err)
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><script>alert(2)</script>` +
+		`<a href="javascript:alert(3)"><rect width="10" onclick='alert(4)'/></a></svg>`
+	sanitized, err := sanitizeSVG([]byte(svg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(sanitized)
+	for _, unsafe := range []string{"<script", "onload", "onclick", "javascript:"} {
+		if strings.Contains(got, unsafe) {
+			t.Fatalf(`sanitizeSVG left %q in output: %v`, unsafe, got)
+		}
+	}
+	if !strings.Contains(got, `<rect width="10"/>`) {
+		t.Fatalf(`sanitizeSVG removed safe content: %v`, got)
+	}
+}
+
+func TestCreateImagePayloadUnsupported(t *testing.T) {
+	imageFile := filepath.Join(t.TempDir(), "slides.pdf")
+	if err := os.WriteFile(imageFile, []byte("%PDF-1.7"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	image, err := createImagePayload(imageFile)
+	if err == nil {
+		t.Fatalf(`createImagePayload(%v) = %v, expected an error instead of a placeholder`, imageFile, image)
+	}
+}
+
+func TestSanitizeSVGBypasses(t *testing.T) {
+	svg := `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">` +
+		`<a href=" javascript&#58;alert(1)"><text>one</text></a>` +
+		`<a xlink:href="data:text/html;base64,PHNjcmlwdD4=">two</a>` +
+		`<a href="vbscript:msgbox"><set attributeName="href" to="javascript:alert(2)"/></a>` +
+		`<animate attributeName="xlink:href" values="javascript:alert(3)"/>` +
+		`<animate attributeName="onclick" to="alert(4)"/>` +
+		`<foreignObject><body onload="alert(5)"/></foreignObject>` +
+		`<style>@import url(https://evil.example.com/x.css);</style>` +
+		// Comments and CDATA sections split the text of a style element
+		`<style>a { background: url(java<!-- -->script:alert(6)) }</style>` +
+		`<style><![CDATA[@im]]>port url(https://evil.example.com/y.css);</style>` +
+		`<style>circle { fill: red }</style>` +
+		`<a href="https://example.com/ok"><use xlink:href="#icon"/></a>` +
+		`<animate attributeName="opacity" from="0" to="1" dur="1s"/></svg>`
+	sanitized, err := sanitizeSVG([]byte(svg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(sanitized)
+	for _, unsafe := range []string{"javascript", "data:", "vbscript", "<set", "alert", "foreignObject", "@import"} {
+		if strings.Contains(got, unsafe) {
+			t.Fatalf(`sanitizeSVG left %q in output: %v`, unsafe, got)
+		}
+	}
+	for _, safe := range []string{`<style>circle { fill: red }</style>`, `<a href="https://example.com/ok"><use xlink:href="#icon"/></a>`, `<animate attributeName="opacity" from="0" to="1" dur="1s"/>`, `<?xml version="1.0"?>`} {
+		if !strings.Contains(got, safe) {
+			t.Fatalf(`sanitizeSVG removed %q: %v`, safe, got)
+		}
+	}
+
+	for _, invalid := range []string{`<svg><script>alert(1)</g></script></svg>`, `<svg><rect>`, `<svg>&bogus;</svg>`} {
+		if _, err := sanitizeSVG([]byte(invalid)); err == nil {
+			t.Fatalf(`sanitizeSVG(%q) succeeded, but need an error for malformed XML`, invalid)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const notebookExtension = ".ipynb"
+
+// ANSI colour codes found in notebook tracebacks
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// notebookText is a notebook string, stored either as one string or as a list of lines
+type notebookText string
+
+func (text *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*text = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*text = notebookText(single)
+	return nil
+}
+
+type notebookOutput struct {
+	OutputType string                  `json:"output_type"`
+	Text       notebookText            `json:"text"`
+	Data       map[string]notebookText `json:"data"`
+	Traceback  []string                `json:"traceback"`
+}
+
+type notebookCell struct {
+	CellType    string                             `json:"cell_type"`
+	Source      notebookText                       `json:"source"`
+	Outputs     []notebookOutput                   `json:"outputs"`
+	Attachments map[string]map[string]notebookText `json:"attachments"`
+}
+
+// Notebook is the subset of the Jupyter nbformat 4 format used to build articles
+type Notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// Decode a base64 PNG from a notebook into an image payload
+func notebookImage(name string, data notebookText) (Image, error) {
+	raw, err := b64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+	if err != nil {
+		return Image{}, fmt.Errorf("Error decoding notebook image %v: %v", name, err)
+	}
+	if _, err := detectMediaType(name+".png", raw); err != nil {
+		return Image{}, err
+	}
+	encoded := b64.StdEncoding.EncodeToString(raw)
+	width, height := imageDimensions("image/png", raw)
+	return Image{Filename: name, Data: &encoded, ContentType: "image/png", Width: width, Height: height, Hash: imageHash(raw)}, nil
+}
+
+// Convert a notebook to markdown. Markdown and raw cells are kept as they are, code cells become
+// fenced code followed by their text outputs, and PNG outputs and attachments are returned as
+// images referenced from the markdown.
+func convertNotebook(data []byte) (string, []Image, error) {
+	var notebook Notebook
+	if err := json.Unmarshal(data, &notebook); err != nil {
+		return "", nil, fmt.Errorf("Error parsing notebook: %v", err)
+	}
+	language := notebook.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = notebook.Metadata.KernelSpec.Language
+	}
+
+	var blocks []string
+	var images []Image
+	for i, cell := range notebook.Cells {
+		source := strings.TrimSpace(string(cell.Source))
+		switch cell.CellType {
+		case "markdown":
+			for _, name := range slices.Sorted(maps.Keys(cell.Attachments)) {
+				png, ok := cell.Attachments[name]["image/png"]
+				if !ok {
+					continue
+				}
+				image, err := notebookImage(fmt.Sprintf("cell-%d-%v", i+1, strings.TrimSuffix(name, ".png")), png)
+				if err != nil {
+					return "", nil, err
+				}
+				images = append(images, image)
+				source = strings.ReplaceAll(source, "(attachment:"+name+")", "("+image.Filename+")")
+			}
+			blocks = append(blocks, source)
+		case "raw":
+			blocks = append(blocks, source)
+		case "code":
+			if source != "" {
+				blocks = append(blocks, fenceCode(source, language))
+			}
+			for j, output := range cell.Outputs {
+				var text string
+				switch output.OutputType {
+				case "stream":
+					text = string(output.Text)
+				case "error":
+					text = ansiEscapePattern.ReplaceAllString(strings.Join(output.Traceback, "\n"), "")
+				case "execute_result", "display_data":
+					if png, ok := output.Data["image/png"]; ok {
+						image, err := notebookImage(fmt.Sprintf("output-%d-%d", i+1, j+1), png)
+						if err != nil {
+							return "", nil, err
+						}
+						images = append(images, image)
+						blocks = append(blocks, fmt.Sprintf("![Output](%v)", image.Filename))
+						continue
+					}
+					text = string(output.Data["text/plain"])
+				}
+				if text = strings.TrimRight(text, "\n"); strings.TrimSpace(text) != "" {
+					blocks = append(blocks, fenceCode(text, "text"))
+				}
+			}
+		}
+	}
+	return strings.Join(blocks, "\n\n"), images, nil
+}
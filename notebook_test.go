@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T) string {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return b64.StdEncoding.EncodeToString(encoded.Bytes())
+}
+
+func testNotebook(t *testing.T) string {
+	return fmt.Sprintf(`{
+  "cells": [
+    {"cell_type": "raw", "source": ["---\n", "title: Plotting Data\n", "---"]},
+    {"cell_type": "markdown", "source": ["# Plotting\n", "\n", "A diagram: ![diagram](attachment:diagram.png)"],
+     "attachments": {"diagram.png": {"image/png": %[1]q}}},
+    {"cell_type": "code", "source": "print(\"hello\")\n1 + 1", "outputs": [
+      {"output_type": "stream", "name": "stdout", "text": ["hello\n"]},
+      {"output_type": "execute_result", "data": {"text/plain": ["2"]}}
+    ]},
+    {"cell_type": "code", "source": ["plot()"], "outputs": [
+      {"output_type": "display_data", "data": {"image/png": %[1]q, "text/plain": ["<Figure>"]}}
+    ]},
+    {"cell_type": "code", "source": ["1 / 0"], "outputs": [
+      {"output_type": "error", "ename": "ZeroDivisionError", "traceback": ["\u001b[0;31mZeroDivisionError\u001b[0m: division by zero"]}
+    ]},
+    {"cell_type": "code", "source": [], "outputs": []}
+  ],
+  "metadata": {"kernelspec": {"language": "python"}, "language_info": {"name": "python"}},
+  "nbformat": 4,
+  "nbformat_minor": 5
+}`, testPNG(t))
+}
+
+func TestConvertNotebook(t *testing.T) {
+	markdown, images, err := convertNotebook([]byte(testNotebook(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"---\ntitle: Plotting Data\n---",
+		"# Plotting\n\nA diagram: ![diagram](cell-2-diagram)",
+		"```python\nprint(\"hello\")\n1 + 1\n```",
+		"```text\nhello\n```",
+		"```text\n2\n```",
+		"```python\nplot()\n```",
+		"![Output](output-4-1)",
+		"```python\n1 / 0\n```",
+		"```text\nZeroDivisionError: division by zero\n```",
+	}, "\n\n")
+	if markdown != want {
+		t.Fatalf("convertNotebook =\n%v\nbut need\n%v", markdown, want)
+	}
+	if len(images) != 2 || images[0].Filename != "cell-2-diagram" || images[1].Filename != "output-4-1" || images[1].ContentType != "image/png" {
+		t.Fatalf(`convertNotebook images = %+v, but need the attachment and the plot`, images)
+	}
+
+	if _, _, err := convertNotebook([]byte(`{"cells": [{"cell_type": "code", "source": "x", "outputs": [{"output_type": "display_data", "data": {"image/png": "bm90IGEgcG5n"}}]}]}`)); err == nil {
+		t.Fatalf("convertNotebook should fail for an image output that is not a png")
+	}
+}
+
+func TestNotebookArticle(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "plotting")
+	writeTestFile(t, filepath.Join(folder, "plotting.ipynb"), testNotebook(t))
+
+	name, articleFile, photos, err := parseArticle(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "plotting" || filepath.Base(articleFile) != "plotting.ipynb" {
+		t.Fatalf(`parseArticle = %v %v, but need the notebook`, name, articleFile)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "Plotting Data" || !strings.HasPrefix(article.Content, "# Plotting") || len(article.Images) != 3 {
+		t.Fatalf(`createArticlePayload = %q %q with %d images, but need the converted notebook and its generated cover`, article.Title, article.Content, len(article.Images))
+	}
+}
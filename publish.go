@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Publishing states an article can be uploaded in
+const (
+	statusDraft     = "draft"
+	statusPublished = "published"
+	statusUnlisted  = "unlisted"
+)
+
+// Resolve the publishing state of an article. Front matter takes precedence over the folder config.
+// Articles scheduled with publish_at are drafts until that time and published after it, so an
+// upload never unpublishes an article that publish-due already published.
+func publishingState(frontMatter FrontMatter, config FolderConfig, now time.Time) (string, *time.Time, error) {
+	status := config.Status
+	if frontMatter.Status != "" {
+		status = frontMatter.Status
+	}
+	switch status {
+	case "", statusDraft, statusPublished, statusUnlisted:
+	default:
+		return "", nil, fmt.Errorf("Invalid status %q, expected draft, published or unlisted", status)
+	}
+
+	publishAt := config.PublishAt
+	if frontMatter.PublishAt != "" {
+		publishAt = frontMatter.PublishAt
+	}
+	if publishAt == "" {
+		return status, nil, nil
+	}
+	publishTime, err := time.Parse(time.RFC3339, publishAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid publish_at %q, expected an RFC3339 timestamp: %v", publishAt, err)
+	}
+	if status == "" || status == statusDraft {
+		status = statusDraft
+		if !publishTime.After(now) {
+			status = statusPublished
+		}
+	}
+	return status, &publishTime, nil
+}
+
+// Drafts whose publish_at time has passed
+func dueArticles(articles []Article, now time.Time) []Article {
+	var due []Article
+	for _, article := range articles {
+		if article.Status == statusDraft && article.PublishAt != nil && !article.PublishAt.After(now) && article.ID != nil {
+			due = append(due, article)
+		}
+	}
+	return due
+}
+
+// Publish every draft on the server whose publish_at time has passed
+func publishDueArticles(now time.Time) ([]Article, error) {
+	// Drafts are only listed for authenticated requests
+	listURL, err := apiURL("GET_ENDPOINT")
+	if err != nil {
+		return nil, err
+	}
+	var articles []Article
+	if err := sendAuthorizedJSON(http.MethodGet, listURL, nil, &articles); err != nil {
+		return nil, fmt.Errorf("Error fetching articles: %v", err)
+	}
+	endpoint, err := apiURL("ENDPOINT")
+	if err != nil {
+		return nil, err
+	}
+	due := dueArticles(articles, now)
+	for _, article := range due {
+		url := endpoint + fmt.Sprintf("%d/", *article.ID)
+		update := map[string]string{"status": statusPublished}
+		if err := sendAuthorizedJSON(http.MethodPatch, url, update, nil); err != nil {
+			return nil, fmt.Errorf("Error publishing article %v: %v", article.Title, err)
+		}
+		logger.Info("Published scheduled article", "article", article.Title, "publish_at", article.PublishAt)
+	}
+	return due, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateArticlePayloadPublishingState(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "status: unlisted\n")
+	writeTestFile(t, filepath.Join(articleFolder, "post.md"), "---\nstatus: draft\npublish_at: 2099-11-01T09:00:00Z\n---\nHello")
+
+	article, err := createArticlePayload("post", filepath.Join(articleFolder, "post.md"), "")
+	want := time.Date(2099, 11, 1, 9, 0, 0, 0, time.UTC)
+	if err != nil || article.Status != "draft" || article.PublishAt == nil || !article.PublishAt.Equal(want) {
+		t.Fatalf(`createArticlePayload = %+v, %v, but need a draft published at %v`, article, err, want)
+	}
+
+	writeTestFile(t, filepath.Join(articleFolder, "post.md"), "Hello")
+	article, err = createArticlePayload("post", filepath.Join(articleFolder, "post.md"), "")
+	if err != nil || article.Status != "unlisted" || article.PublishAt != nil {
+		t.Fatalf(`createArticlePayload = %+v, %v, but need the folder config status`, article, err)
+	}
+}
+
+func TestPublishingStateScheduled(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		frontMatter FrontMatter
+		want        string
+	}{
+		{FrontMatter{PublishAt: "2026-10-20T09:00:00Z"}, statusDraft},
+		{FrontMatter{PublishAt: "2026-10-18T09:00:00Z"}, statusPublished},
+		{FrontMatter{Status: "draft", PublishAt: "2026-10-20T09:00:00Z"}, statusDraft},
+		{FrontMatter{Status: "draft", PublishAt: "2026-10-18T09:00:00Z"}, statusPublished},
+		{FrontMatter{Status: "unlisted", PublishAt: "2026-10-18T09:00:00Z"}, statusUnlisted},
+		{FrontMatter{Status: "draft"}, statusDraft},
+	}
+	for _, c := range cases {
+		if status, _, err := publishingState(c.frontMatter, FolderConfig{}, now); err != nil || status != c.want {
+			t.Errorf(`publishingState(%+v) = %q, %v, but need %q`, c.frontMatter, status, err, c.want)
+		}
+	}
+}
+
+func TestPublishingStateInvalid(t *testing.T) {
+	if _, _, err := publishingState(FrontMatter{Status: "live"}, FolderConfig{}, time.Now()); err == nil {
+		t.Fatalf(`publishingState expected an error for an invalid status`)
+	}
+	if _, _, err := publishingState(FrontMatter{PublishAt: "next tuesday"}, FolderConfig{}, time.Now()); err == nil {
+		t.Fatalf(`publishingState expected an error for an invalid publish_at`)
+	}
+}
+
+func TestPublishDueArticles(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	due, notDue, published := 1, 2, 3
+	var patched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode([]Article{
+				{ID: &due, Title: "Due", Status: "draft", PublishAt: &past},
+				{ID: &notDue, Title: "Not due", Status: "draft", PublishAt: &future},
+				{ID: &published, Title: "Published", Status: "published", PublishAt: &past},
+			})
+			return
+		}
+		var update map[string]string
+		json.NewDecoder(r.Body).Decode(&update)
+		if r.Method != http.MethodPatch || update["status"] != "published" {
+			t.Errorf("Unexpected %v request with body %v", r.Method, update)
+		}
+		patched = append(patched, r.URL.Path)
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("GET_ENDPOINT", "api/articles/")
+	t.Setenv("ENDPOINT", "api/articles/")
+
+	articles, err := publishDueArticles(now)
+	if err != nil || len(articles) != 1 || articles[0].Title != "Due" {
+		t.Fatalf(`publishDueArticles = %v, %v, but need only the due draft`, articles, err)
+	}
+	if len(patched) != 1 || patched[0] != "/api/articles/1/" {
+		t.Fatalf(`publishDueArticles patched %v, but need [/api/articles/1/]`, patched)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lines containing the allow marker, e.g. in a <!-- secret-scan:allow --> comment, are not scanned
+const secretAllowMarker = "secret-scan:allow"
+
+const (
+	defaultEntropyThreshold = 4.5
+	minEntropyTokenLength   = 24
+)
+
+type secretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Credential formats that are always reported
+var secretRules = []secretRule{
+	{"AWS access key id", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"AWS secret access key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+}
+
+// Candidates for the entropy check, long runs of base64 or hex characters
+var entropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_=-]{24,}`)
+
+// A secretFinding is a likely credential found on a line of text
+type secretFinding struct {
+	Rule  string
+	Match string
+	Line  int
+}
+
+// Shannon entropy of a string in bits per character
+func shannonEntropy(text string) float64 {
+	counts := map[rune]int{}
+	for _, r := range text {
+		counts[r]++
+	}
+	var entropy float64
+	length := float64(utf8.RuneCountInString(text))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Random looking tokens mix upper case, lower case and digits
+func mixedCharacterClasses(token string) bool {
+	var upper, lower, digit bool
+	for _, r := range token {
+		upper = upper || unicode.IsUpper(r)
+		lower = lower || unicode.IsLower(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	return upper && lower && digit
+}
+
+// Show only the ends of a secret so reports do not leak it again
+func redactSecret(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + "…" + secret[len(secret)-4:]
+}
+
+// Match internal hostnames, e.g. corp.example.com matches build.corp.example.com
+func internalHostPattern(hosts []string) *regexp.Regexp {
+	var alternatives []string
+	for _, host := range hosts {
+		host = strings.TrimPrefix(strings.TrimSpace(host), "*.")
+		if host != "" {
+			alternatives = append(alternatives, regexp.QuoteMeta(host))
+		}
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)*(?:` + strings.Join(alternatives, "|") + `)\b`)
+}
+
+// SecretScanner finds credentials, high entropy strings and internal hostnames in text
+type SecretScanner struct {
+	EntropyThreshold float64
+	InternalHosts    *regexp.Regexp
+}
+
+// Configure the scanner from SECRET_ENTROPY_THRESHOLD and the comma separated SECRET_INTERNAL_HOSTS
+func newSecretScanner() (SecretScanner, error) {
+	scanner := SecretScanner{EntropyThreshold: defaultEntropyThreshold}
+	if threshold := os.Getenv("SECRET_ENTROPY_THRESHOLD"); threshold != "" {
+		value, err := strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			return scanner, fmt.Errorf("Invalid SECRET_ENTROPY_THRESHOLD %q: %v", threshold, err)
+		}
+		scanner.EntropyThreshold = value
+	}
+	if hosts := os.Getenv("SECRET_INTERNAL_HOSTS"); hosts != "" {
+		scanner.InternalHosts = internalHostPattern(strings.Split(hosts, ","))
+	}
+	return scanner, nil
+}
+
+// Scan every line of text that does not carry the allow marker
+func (scanner SecretScanner) scan(text string) []secretFinding {
+	var findings []secretFinding
+	for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.Contains(line, secretAllowMarker) {
+			continue
+		}
+		found := map[string]bool{}
+		for _, rule := range secretRules {
+			for _, match := range rule.Pattern.FindAllString(line, -1) {
+				findings = append(findings, secretFinding{Rule: rule.Name, Match: match, Line: i + 1})
+				found[match] = true
+			}
+		}
+		if scanner.InternalHosts != nil {
+			for _, match := range scanner.InternalHosts.FindAllString(line, -1) {
+				findings = append(findings, secretFinding{Rule: "internal hostname", Match: match, Line: i + 1})
+			}
+		}
+		for _, token := range entropyTokenPattern.FindAllString(line, -1) {
+			if len(token) < minEntropyTokenLength || !mixedCharacterClasses(token) || shannonEntropy(token) < scanner.EntropyThreshold {
+				continue
+			}
+			covered := false
+			for match := range found {
+				covered = covered || strings.Contains(match, token) || strings.Contains(token, match)
+			}
+			if !covered {
+				findings = append(findings, secretFinding{Rule: "high entropy string", Match: token, Line: i + 1})
+			}
+		}
+	}
+	return findings
+}
+
+func (finding secretFinding) message() string {
+	if finding.Rule == "internal hostname" {
+		return fmt.Sprintf("Possible internal hostname %v, add %v to the line if it is safe to publish", finding.Match, secretAllowMarker)
+	}
+	return fmt.Sprintf("Possible %v %v, add %v to the line if it is not a secret", finding.Rule, redactSecret(finding.Match), secretAllowMarker)
+}
+
+// Attachments are scanned when they are text, judged by content type or by being valid UTF-8 without NUL bytes
+func isTextAttachment(attachment Attachment, data []byte) bool {
+	if strings.HasPrefix(attachment.ContentType, "text/") {
+		return true
+	}
+	for _, textType := range []string{"json", "xml", "yaml", "javascript", "x-sh"} {
+		if strings.Contains(attachment.ContentType, textType) {
+			return true
+		}
+	}
+	return utf8.Valid(data) && !strings.ContainsRune(string(data), 0)
+}
+
+// Scan an article's content and text attachments for secrets. Findings in the content are
+// placed on the file and line they were written on through the content's source map.
+func scanArticleSecrets(content string, sourceMap *SourceMap, articleFile string, attachments []Attachment) ([]Diagnostic, error) {
+	scanner, err := newSecretScanner()
+	if err != nil {
+		return nil, err
+	}
+	var contentDiagnostics []Diagnostic
+	for _, finding := range scanner.scan(content) {
+		contentDiagnostics = append(contentDiagnostics, Diagnostic{Severity: severityError, Line: finding.Line, Message: finding.message()})
+	}
+	diagnostics := sourceMap.locate(contentDiagnostics, articleFile)
+	for _, attachment := range attachments {
+		data, err := b64.StdEncoding.DecodeString(attachment.Data)
+		if err != nil || !isTextAttachment(attachment, data) {
+			continue
+		}
+		for _, finding := range scanner.scan(string(data)) {
+			diagnostics = append(diagnostics, Diagnostic{Severity: severityError, File: attachment.Filepath, Line: finding.Line, Message: finding.message()})
+		}
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// {{< name arg key="value" >}} opening tags and {{< /name >}} closing tags
+var shortcodeTagPattern = regexp.MustCompile(`\{\{<\s*(/?)\s*([\w-]+)((?:\s+(?:[\w-]+=)?(?:"(?:[^"\\\n]|\\.)*"|[^\s">]+))*)\s*>\}\}`)
+
+// Positional arguments and key=value parameters, either of which may be quoted
+var shortcodeArgPattern = regexp.MustCompile(`(?:([\w-]+)=)?("(?:[^"\\]|\\.)*"|[^\s"]+)`)
+
+// A Shortcode is a {{< name >}} tag found in an article. Paired shortcodes wrap the
+// content up to their {{< /name >}} closing tag, which is expanded before the shortcode.
+type Shortcode struct {
+	Name   string
+	Args   []string
+	Params map[string]string
+	Inner  string
+}
+
+// The file a shortcode was found in and the includes that led to it, with the article's
+// photos folder and photo metadata
+type shortcodeContext struct {
+	File   string
+	Root   string
+	Stack  []string
+	Photos string
+	Images map[string]ImageMetadata
+}
+
+type shortcodeDefinition struct {
+	// Paired shortcodes wrap content and are closed with {{< /name >}}
+	Paired bool
+	Expand func(context shortcodeContext, shortcode Shortcode) (string, error)
+}
+
+var shortcodeRegistry = map[string]shortcodeDefinition{}
+
+// Register a shortcode handler by name, replacing any existing one
+func registerShortcode(name string, definition shortcodeDefinition) {
+	shortcodeRegistry[name] = definition
+}
+
+func init() {
+	registerShortcode("include", shortcodeDefinition{Expand: expandInclude})
+	registerShortcode("code", shortcodeDefinition{Expand: expandCode})
+	registerShortcode("youtube", shortcodeDefinition{Expand: expandYouTube})
+	registerShortcode("gist", shortcodeDefinition{Expand: expandGist})
+	registerShortcode("tweet", shortcodeDefinition{Expand: expandTweet})
+	registerShortcode("callout", shortcodeDefinition{Paired: true, Expand: expandCallout})
+	registerShortcode("gallery", shortcodeDefinition{Expand: expandGallery})
+}
+
+// Positional argument i, or the named parameter, or an error naming the shortcode's usage
+func (shortcode Shortcode) arg(i int, name string) (string, error) {
+	if value, ok := shortcode.Params[name]; ok {
+		return value, nil
+	}
+	if i < len(shortcode.Args) {
+		return shortcode.Args[i], nil
+	}
+	return "", fmt.Errorf("Shortcode %v is missing its %v argument", shortcode.Name, name)
+}
+
+func parseShortcodeArgs(text string) ([]string, map[string]string, error) {
+	var args []string
+	params := map[string]string{}
+	for _, match := range shortcodeArgPattern.FindAllStringSubmatch(text, -1) {
+		value := match[2]
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Invalid quoted argument %v", value)
+			}
+			value = unquoted
+		}
+		if match[1] != "" {
+			params[match[1]] = value
+		} else {
+			args = append(args, value)
+		}
+	}
+	return args, params, nil
+}
+
+type shortcodeTag struct {
+	start, end int
+	line       int
+	closing    bool
+	name       string
+	args       string
+}
+
+// Expands the shortcode tags of one piece of content, collecting problems as diagnostics
+type shortcodeExpander struct {
+	content     string
+	tags        []shortcodeTag
+	next        int
+	context     shortcodeContext
+	diagnostics []Diagnostic
+}
+
+// shortcodeErrors are the problems found in content a shortcode expanded, such as an included file
+type shortcodeErrors []Diagnostic
+
+func (errs shortcodeErrors) Error() string {
+	var messages []string
+	for _, diagnostic := range errs {
+		messages = append(messages, diagnostic.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (expander *shortcodeExpander) fail(tag shortcodeTag, err error) {
+	var nested shortcodeErrors
+	if errors.As(err, &nested) {
+		for _, diagnostic := range nested {
+			expander.diagnostics = append(expander.diagnostics, Diagnostic{Severity: diagnostic.Severity, Line: tag.line, Message: diagnostic.Message})
+		}
+		return
+	}
+	expander.diagnostics = append(expander.diagnostics, Diagnostic{Severity: severityError, Line: tag.line, Message: err.Error()})
+}
+
+// Expand the content from offset up to the closing tag of the shortcode named until, returning
+// the expanded content, the offset after the closing tag and whether the closing tag was found.
+// Lines written by a shortcode come from the line of its tag.
+func (expander *shortcodeExpander) expand(offset int, until string) (*lineTracker, int, bool) {
+	expanded := &lineTracker{source: expander.content}
+	for expander.next < len(expander.tags) {
+		tag := expander.tags[expander.next]
+		expander.next++
+		expanded.copy(offset, tag.start)
+		offset = tag.end
+		start, end := tag.start, tag.end
+
+		if tag.closing {
+			if tag.name == until {
+				return expanded, tag.end, true
+			}
+			expander.fail(tag, fmt.Errorf("Closing shortcode %v does not match an open shortcode", tag.name))
+			expanded.copy(start, end)
+			continue
+		}
+		definition, ok := shortcodeRegistry[tag.name]
+		if !ok {
+			expander.fail(tag, fmt.Errorf("Unknown shortcode %q", tag.name))
+			expanded.copy(start, end)
+			continue
+		}
+		args, params, err := parseShortcodeArgs(tag.args)
+		if err != nil {
+			expander.fail(tag, err)
+			expanded.copy(start, end)
+			continue
+		}
+		shortcode := Shortcode{Name: tag.name, Args: args, Params: params}
+		if definition.Paired {
+			inner, innerEnd, closed := expander.expand(tag.end, tag.name)
+			if !closed {
+				expander.fail(tag, fmt.Errorf("Shortcode %v is not closed with {{< /%v >}}", tag.name, tag.name))
+				expanded.copy(start, end)
+				expanded.append(inner)
+				return expanded, innerEnd, false
+			}
+			shortcode.Inner = strings.TrimSpace(inner.String())
+			end = innerEnd
+			offset = end
+		}
+		result, err := definition.Expand(expander.context, shortcode)
+		if err != nil {
+			expander.fail(tag, err)
+			expanded.copy(start, end)
+			continue
+		}
+		expanded.write(result, tag.line-1)
+	}
+	expanded.copy(offset, len(expander.content))
+	return expanded, len(expander.content), false
+}
+
+// Expand the shortcodes in content, which was read from the file in context. Tags in fenced
+// code blocks and inline code are left alone. Returns the expanded content with the line of the
+// content each of its lines came from. Problems are reported as errors on the tag's line in the
+// content.
+func expandShortcodes(content string, context shortcodeContext) (string, []int, []Diagnostic) {
+	lines, inCode := markdownLines(content)
+	content = strings.Join(lines, "\n")
+	// Offsets of the inline code spans in the content
+	var inlineCode [][]int
+	offset := 0
+	for i, line := range lines {
+		if !inCode[i] {
+			for _, span := range inlineCodePattern.FindAllStringIndex(line, -1) {
+				inlineCode = append(inlineCode, []int{offset + span[0], offset + span[1]})
+			}
+		}
+		offset += len(line) + 1
+	}
+	expander := &shortcodeExpander{content: content, context: context}
+	for _, match := range shortcodeTagPattern.FindAllStringSubmatchIndex(content, -1) {
+		line := strings.Count(content[:match[0]], "\n")
+		if inCode[line] || slices.ContainsFunc(inlineCode, func(span []int) bool { return span[0] < match[0] && match[0] < span[1] }) {
+			continue
+		}
+		expander.tags = append(expander.tags, shortcodeTag{
+			start:   match[0],
+			end:     match[1],
+			line:    line + 1,
+			closing: content[match[2]:match[3]] == "/",
+			name:    content[match[4]:match[5]],
+			args:    content[match[6]:match[7]],
+		})
+	}
+	expanded, _, _ := expander.expand(0, "")
+	return expanded.String(), expanded.origins, expander.diagnostics
+}
+
+// Expand the shortcodes in an article's content
+func expandArticleShortcodes(content string, articleFile string, articlePhotos string, images map[string]ImageMetadata) (string, []int, []Diagnostic) {
+	file, err := filepath.Abs(articleFile)
+	if err != nil {
+		file = articleFile
+	}
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	return expandShortcodes(content, shortcodeContext{File: file, Root: repoRoot(filepath.Dir(articleFile)), Stack: []string{file}, Photos: articlePhotos, Images: images})
+}
+
+var youTubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// {{< youtube dQw4w9WgXcQ title="..." >}}
+func expandYouTube(_ shortcodeContext, shortcode Shortcode) (string, error) {
+	id, err := shortcode.arg(0, "id")
+	if err != nil {
+		return "", err
+	}
+	if !youTubeIDPattern.MatchString(id) {
+		return "", fmt.Errorf("Invalid YouTube video id %q", id)
+	}
+	title := shortcode.Params["title"]
+	if title == "" {
+		title = "YouTube video"
+	}
+	return fmt.Sprintf(`<div class="embed embed-youtube"><iframe src="https://www.youtube-nocookie.com/embed/%v" title="%v" loading="lazy" allow="accelerometer; clipboard-write; encrypted-media; gyroscope; picture-in-picture" allowfullscreen></iframe></div>`,
+		id, html.EscapeString(title)), nil
+}
+
+var gistPattern = regexp.MustCompile(`^[\w-]+/[0-9a-f]+$`)
+
+// {{< gist user/id file="main.go" >}}
+func expandGist(_ shortcodeContext, shortcode Shortcode) (string, error) {
+	gist, err := shortcode.arg(0, "gist")
+	if err != nil {
+		return "", err
+	}
+	if !gistPattern.MatchString(gist) {
+		return "", fmt.Errorf("Invalid gist %q, expected user/id", gist)
+	}
+	src := "https://gist.github.com/" + gist + ".js"
+	if file, err := shortcode.arg(1, "file"); err == nil {
+		src += "?file=" + url.QueryEscape(file)
+	}
+	return fmt.Sprintf(`<script src="%v"></script>`, html.EscapeString(src)), nil
+}
+
+// {{< tweet https://x.com/user/status/123 >}}
+func expandTweet(_ shortcodeContext, shortcode Shortcode) (string, error) {
+	tweet, err := shortcode.arg(0, "url")
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(tweet)
+	if err != nil || parsed.Scheme != "https" || !slices.Contains([]string{"twitter.com", "x.com", "www.twitter.com", "www.x.com"}, parsed.Host) || !strings.Contains(parsed.Path, "/status/") {
+		return "", fmt.Errorf("Invalid tweet url %q, expected https://x.com/user/status/id", tweet)
+	}
+	return fmt.Sprintf(`<blockquote class="twitter-tweet"><a href="%v"></a></blockquote><script async src="https://platform.twitter.com/widgets.js"></script>`,
+		html.EscapeString(tweet)), nil
+}
+
+// {{< callout warning title="..." >}}markdown{{< /callout >}}
+func expandCallout(context shortcodeContext, shortcode Shortcode) (string, error) {
+	kind, err := shortcode.arg(0, "type")
+	if err != nil {
+		return "", err
+	}
+	return renderCallout(context.Root, kind, shortcode.Params["title"], shortcode.Inner)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseShortcodeArgs(t *testing.T) {
+	args, params, err := parseShortcodeArgs(` warning title="Read \"this\" first" level=2 "second arg"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantArgs := []string{"warning", "second arg"}
+	wantParams := map[string]string{"title": `Read "this" first`, "level": "2"}
+	if !reflect.DeepEqual(args, wantArgs) || !reflect.DeepEqual(params, wantParams) {
+		t.Fatalf(`parseShortcodeArgs = %q %q, but need %q %q`, args, params, wantArgs, wantParams)
+	}
+}
+
+func TestExpandShortcodes(t *testing.T) {
+	content := strings.Join([]string{
+		`{{< youtube dQw4w9WgXcQ title="A <video>" >}}`,
+		`{{< gist octocat/6cad326836d38bd3a7ae file="hello.go" >}}`,
+		`{{< tweet "https://x.com/golang/status/123" >}}`,
+		`{{< callout warning title="Careful" >}}`,
+		`Outer text`,
+		`{{< callout tip >}}Inner **tip**{{< /callout >}}`,
+		`{{< /callout >}}`,
+		"```",
+		`{{< youtube notexpanded >}}`,
+		"```",
+	}, "\n")
+	want := strings.Join([]string{
+		`<div class="embed embed-youtube"><iframe src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ" title="A &lt;video&gt;" loading="lazy" allow="accelerometer; clipboard-write; encrypted-media; gyroscope; picture-in-picture" allowfullscreen></iframe></div>`,
+		`<script src="https://gist.github.com/octocat/6cad326836d38bd3a7ae.js?file=hello.go"></script>`,
+		`<blockquote class="twitter-tweet"><a href="https://x.com/golang/status/123"></a></blockquote><script async src="https://platform.twitter.com/widgets.js"></script>`,
+		`<div class="callout callout-warning">`,
+		`<p class="callout-title">Careful</p>`,
+		``,
+		`Outer text`,
+		`<div class="callout callout-tip">`,
+		`<p class="callout-title">Tip</p>`,
+		``,
+		`Inner **tip**`,
+		``,
+		`</div>`,
+		``,
+		`</div>`,
+		"```",
+		`{{< youtube notexpanded >}}`,
+		"```",
+	}, "\n")
+
+	got, origins, diagnostics := expandShortcodes(content, shortcodeContext{})
+	if got != want {
+		t.Fatalf("expandShortcodes =\n%v\nbut need\n%v", got, want)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf(`expandShortcodes diagnostics = %v, but need none`, diagnostics)
+	}
+	// The lines of the outer callout come from its opening tag
+	wantOrigins := []int{0, 1, 2, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 7, 8, 9}
+	if !reflect.DeepEqual(origins, wantOrigins) {
+		t.Fatalf(`expandShortcodes origins = %v, but need %v`, origins, wantOrigins)
+	}
+}
+
+func TestExpandShortcodesErrors(t *testing.T) {
+	content := strings.Join([]string{
+		`{{< vimeo 123 >}}`,
+		`{{< /callout >}}`,
+		`{{< callout danger >}}Oops{{< /callout >}}`,
+		`{{< tweet https://example.com/status/1 >}}`,
+		`{{< youtube >}}`,
+		"Write `{{< vimeo 123 >}}` to embed a video",
+		`{{< callout note >}}`,
+		`Never closed`,
+	}, "\n")
+	got, _, diagnostics := expandShortcodes(content, shortcodeContext{})
+	if got != content {
+		t.Fatalf("expandShortcodes =\n%v\nbut need the content unchanged", got)
+	}
+	wantDiagnostics := []Diagnostic{
+		{Severity: severityError, Line: 1, Message: `Unknown shortcode "vimeo"`},
+		{Severity: severityError, Line: 2, Message: "Closing shortcode callout does not match an open shortcode"},
+		{Severity: severityError, Line: 3, Message: `Unknown callout type "danger", expected one of caution, important, note, tip, warning`},
+		{Severity: severityError, Line: 4, Message: `Invalid tweet url "https://example.com/status/1", expected https://x.com/user/status/id`},
+		{Severity: severityError, Line: 5, Message: "Shortcode youtube is missing its id argument"},
+		{Severity: severityError, Line: 7, Message: "Shortcode callout is not closed with {{< /callout >}}"},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf("expandShortcodes diagnostics =\n%v\nbut need\n%v", diagnostics, wantDiagnostics)
+	}
+}
+
+func TestRegisterShortcode(t *testing.T) {
+	registerShortcode("upper", shortcodeDefinition{Paired: true, Expand: func(_ shortcodeContext, shortcode Shortcode) (string, error) {
+		return strings.ToUpper(shortcode.Inner), nil
+	}})
+	t.Cleanup(func() { delete(shortcodeRegistry, "upper") })
+
+	got, _, diagnostics := expandShortcodes(`{{< upper >}} shout {{< /upper >}}!`, shortcodeContext{})
+	if got != "SHOUT!" || len(diagnostics) != 0 {
+		t.Fatalf(`expandShortcodes = %q %v, but need "SHOUT!"`, got, diagnostics)
+	}
+}
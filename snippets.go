@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Fenced code block languages by file extension, or by file name for files without one
+var codeLanguages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".ts": "typescript", ".jsx": "jsx", ".tsx": "tsx",
+	".rs": "rust", ".java": "java", ".kt": "kotlin", ".c": "c", ".h": "c", ".cpp": "cpp", ".cs": "csharp",
+	".rb": "ruby", ".php": "php", ".swift": "swift", ".sh": "bash", ".bash": "bash", ".zsh": "zsh",
+	".ps1": "powershell", ".sql": "sql", ".html": "html", ".css": "css", ".scss": "scss", ".json": "json",
+	".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".xml": "xml", ".md": "markdown", ".proto": "protobuf",
+	".tf": "hcl", ".lua": "lua",
+	"Dockerfile": "dockerfile", "Makefile": "makefile",
+}
+
+// Language of a fenced code block for a file, empty when it is not known
+func codeLanguage(file string) string {
+	if language, ok := codeLanguages[filepath.Base(file)]; ok {
+		return language
+	}
+	return codeLanguages[strings.ToLower(filepath.Ext(file))]
+}
+
+// Resolve a snippet path and make sure it stays inside the repository. Paths starting with
+// ./ or ../ are relative to the including file, anything else to the repository root.
+func resolveSnippetPath(target string, includingFile string, root string) (string, error) {
+	path := filepath.Join(root, filepath.FromSlash(target))
+	if strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") {
+		path = filepath.Join(filepath.Dir(includingFile), filepath.FromSlash(target))
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if !insideFolder(absRoot, absPath) {
+		return "", fmt.Errorf("Included file %v is outside the repository", target)
+	}
+	// Follow symlinks so a link inside the repository cannot point outside it
+	if resolvedRoot, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = resolvedRoot
+	}
+	resolvedPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return "", fmt.Errorf("Included file %v does not exist", target)
+	}
+	if !insideFolder(absRoot, resolvedPath) {
+		return "", fmt.Errorf("Included file %v is outside the repository", target)
+	}
+	return resolvedPath, nil
+}
+
+func insideFolder(folder string, path string) bool {
+	relative, err := filepath.Rel(folder, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// Parse a lines="10-40" option into a one-based inclusive range. A single number selects
+// one line and an open end such as 10- runs to the end of the file.
+func parseLineRange(lines string, total int) (int, int, error) {
+	if lines == "" {
+		return 1, total, nil
+	}
+	startText, endText, isRange := strings.Cut(lines, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(startText))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid lines %q, expected a range like 10-40", lines)
+	}
+	end := start
+	if isRange {
+		end = total
+		if strings.TrimSpace(endText) != "" {
+			if end, err = strconv.Atoi(strings.TrimSpace(endText)); err != nil {
+				return 0, 0, fmt.Errorf("Invalid lines %q, expected a range like 10-40", lines)
+			}
+		}
+	}
+	if start < 1 || end < start || end > total {
+		return 0, 0, fmt.Errorf("Lines %q are outside the %d lines of the file", lines, total)
+	}
+	return start, end, nil
+}
+
+// Wrap code in a fence long enough not to be closed by any backticks in the code
+func fenceCode(code string, language string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// {{< code "cmd/server/main.go" lines="10-40" lang="go" >}} embeds lines of a file as a code block
+func expandCode(context shortcodeContext, shortcode Shortcode) (string, error) {
+	target, err := shortcode.arg(0, "file")
+	if err != nil {
+		return "", err
+	}
+	path, err := resolveSnippetPath(target, context.File, context.Root)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Error reading included file %v: %v", target, err)
+	}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+	start, end, err := parseLineRange(shortcode.Params["lines"], len(lines))
+	if err != nil {
+		return "", fmt.Errorf("Error including %v: %v", target, err)
+	}
+	language, ok := shortcode.Params["lang"]
+	if !ok {
+		language = codeLanguage(path)
+	}
+	return fenceCode(strings.Join(lines[start-1:end], "\n"), language), nil
+}
+
+// {{< include "snippets/intro.md" >}} includes another markdown file, expanding its shortcodes
+func expandInclude(context shortcodeContext, shortcode Shortcode) (string, error) {
+	target, err := shortcode.arg(0, "file")
+	if err != nil {
+		return "", err
+	}
+	path, err := resolveSnippetPath(target, context.File, context.Root)
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(context.Stack, path) {
+		return "", fmt.Errorf("Include cycle: %v includes %v again", filepath.Base(context.File), target)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Error reading included file %v: %v", target, err)
+	}
+	_, body, err := parseFrontMatter(string(data))
+	if err != nil {
+		return "", fmt.Errorf("Error reading front matter of %v: %v", target, err)
+	}
+	included := shortcodeContext{File: path, Root: context.Root, Stack: append(slices.Clone(context.Stack), path)}
+	expanded, _, diagnostics := expandShortcodes(strings.TrimSpace(body), included)
+	if len(diagnostics) > 0 {
+		for i := range diagnostics {
+			diagnostics[i].Message = fmt.Sprintf("%v (in %v)", diagnostics[i].Message, target)
+		}
+		return "", shortcodeErrors(diagnostics)
+	}
+	return rebaseRelativeLinks(expanded, filepath.Dir(path), filepath.Dir(context.File)), nil
+}
+
+// Rewrite the relative links of content written in folder from so they point at the same
+// files from folder to. Targets that do not exist, such as photo names, are left alone.
+func rebaseRelativeLinks(content string, from string, to string) string {
+	if from == to {
+		return content
+	}
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] || !strings.Contains(line, "](") {
+			continue
+		}
+		lines[i] = markdownLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+			parts := markdownLinkPattern.FindStringSubmatch(link)
+			if !isRelativeLink(parts[2]) {
+				return link
+			}
+			target, fragment, hasFragment := strings.Cut(parts[2], "#")
+			path := filepath.Join(from, filepath.FromSlash(target))
+			if _, err := os.Stat(path); err != nil {
+				return link
+			}
+			rebased, err := filepath.Rel(to, path)
+			if err != nil {
+				return link
+			}
+			rebased = filepath.ToSlash(rebased)
+			if hasFragment {
+				rebased += "#" + fragment
+			}
+			return parts[1] + rebased + parts[3]
+		})
+	}
+	return strings.Join(lines, "\n")
+}
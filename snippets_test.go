@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandArticleSnippets(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	writeTestFile(t, filepath.Join(root, "cmd", "server", "main.go"), "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	writeTestFile(t, filepath.Join(root, "snippets", "setup.md"), "---\ntitle: Setup\n---\nInstall Go first.\n\n{{< include \"snippets/step.md\" >}}")
+	writeTestFile(t, filepath.Join(root, "snippets", "step.md"), "Then run `go build` as in [the guide](guide.md#build), not `{{< nope >}}`.")
+	writeTestFile(t, filepath.Join(root, "snippets", "guide.md"), "")
+	articleFile := filepath.Join(root, "articles", "server", "server.md")
+	writeTestFile(t, articleFile, "")
+	writeTestFile(t, filepath.Join(root, "articles", "server", "local.md"), "A local note.")
+
+	content := strings.Join([]string{
+		`{{< include "snippets/setup.md" >}}`,
+		`{{< code "cmd/server/main.go" lines="5-7" >}}`,
+		`{{< include "./local.md" >}}`,
+		"```",
+		`{{< include "snippets/setup.md" >}}`,
+		"```",
+	}, "\n")
+	want := strings.Join([]string{
+		"Install Go first.\n\nThen run `go build` as in [the guide](../../snippets/guide.md#build), not `{{< nope >}}`.",
+		"```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		"A local note.",
+		"```",
+		`{{< include "snippets/setup.md" >}}`,
+		"```",
+	}, "\n")
+
+	got, _, diagnostics := expandArticleShortcodes(content, articleFile, "", nil)
+	if got != want {
+		t.Fatalf("expandArticleShortcodes =\n%v\nbut need\n%v", got, want)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf(`expandArticleShortcodes diagnostics = %v, but need none`, diagnostics)
+	}
+}
+
+func TestExpandArticleSnippetsErrors(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	writeTestFile(t, filepath.Join(root, "snippets", "a.md"), `{{< include "snippets/b.md" >}}`)
+	writeTestFile(t, filepath.Join(root, "snippets", "b.md"), `{{< include "snippets/a.md" >}}`)
+	writeTestFile(t, filepath.Join(root, "snippets", "broken.md"), "{{< vimeo 1 >}}\n{{< youtube >}}")
+	writeTestFile(t, filepath.Join(root, "short.go"), "package main\n")
+	articleFile := filepath.Join(root, "article", "article.md")
+	writeTestFile(t, articleFile, "")
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	writeTestFile(t, outside, "secret")
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	content := strings.Join([]string{
+		`{{< include "snippets/a.md" >}}`,
+		`{{< code "../../etc/passwd" >}}`,
+		`{{< code "link.txt" >}}`,
+		`{{< code "short.go" lines="2-9" >}}`,
+		`{{< include "missing.md" >}}`,
+		`{{< include "snippets/broken.md" >}}`,
+	}, "\n")
+	_, _, diagnostics := expandArticleShortcodes(content, articleFile, "", nil)
+	wantDiagnostics := []Diagnostic{
+		{Severity: severityError, Line: 1, Message: "Include cycle: b.md includes snippets/a.md again (in snippets/b.md) (in snippets/a.md)"},
+		{Severity: severityError, Line: 2, Message: "Included file ../../etc/passwd is outside the repository"},
+		{Severity: severityError, Line: 3, Message: "Included file link.txt is outside the repository"},
+		{Severity: severityError, Line: 4, Message: `Error including short.go: Lines "2-9" are outside the 1 lines of the file`},
+		{Severity: severityError, Line: 5, Message: "Included file missing.md does not exist"},
+		{Severity: severityError, Line: 6, Message: `Unknown shortcode "vimeo" (in snippets/broken.md)`},
+		{Severity: severityError, Line: 6, Message: "Shortcode youtube is missing its id argument (in snippets/broken.md)"},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf("expandArticleShortcodes diagnostics =\n%v\nbut need\n%v", diagnostics, wantDiagnostics)
+	}
+}
+
+func TestFenceCode(t *testing.T) {
+	got := fenceCode("Use ```go fences", "markdown")
+	want := "````markdown\nUse ```go fences\n````"
+	if got != want {
+		t.Fatalf(`fenceCode = %q, but need %q`, got, want)
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Starter dictionaries bundled with the uploader, used when the repository does not have its own
+//
+//go:embed dictionaries/*.aff dictionaries/*.dic
+var bundledDictionaries embed.FS
+
+const (
+	defaultDictionary  = "en_US"
+	dictionariesFolder = "dictionaries"
+	customWordsFile    = ".spelling"
+	maxSuggestions     = 3
+)
+
+// Spell check modes set with SPELLCHECK
+const (
+	spellcheckWarn  = "warn"
+	spellcheckBlock = "block"
+)
+
+// An affixRule adds an affix to words matching its condition, after stripping characters
+type affixRule struct {
+	Strip     string
+	Add       string
+	Condition *regexp.Regexp
+}
+
+type affixClass struct {
+	Prefix bool
+	// Whether the class combines with affixes of the other kind
+	Cross bool
+	Rules []affixRule
+}
+
+// A Dictionary holds every word form of a Hunspell dictionary, expanded from its affix rules
+type Dictionary struct {
+	Words        map[string]bool
+	Try          string
+	Replacements [][2]string
+	// ICONV conversions applied to words before they are checked
+	Conversions [][2]string
+}
+
+// Hunspell dictionaries are UTF-8 or ISO-8859-1 (Latin-1), set with SET in the affix file
+var dictionaryEncodingPattern = regexp.MustCompile(`(?m)^SET[ \t]+(\S+)`)
+
+// Convert the text of a dictionary in the encoding named by its affix file to UTF-8
+func decodeDictionary(aff string, text string) (string, error) {
+	match := dictionaryEncodingPattern.FindStringSubmatch(aff)
+	if match == nil {
+		return text, nil
+	}
+	switch strings.ToUpper(match[1]) {
+	case "UTF-8":
+		return text, nil
+	case "ISO8859-1", "ISO-8859-1":
+		// Latin-1 bytes are the first 256 code points
+		runes := make([]rune, len(text))
+		for i := 0; i < len(text); i++ {
+			runes[i] = rune(text[i])
+		}
+		return string(runes), nil
+	default:
+		return "", fmt.Errorf("Dictionary encoding %v is not supported, convert the dictionary to UTF-8", match[1])
+	}
+}
+
+// Split the flags of a dictionary entry according to the FLAG setting of the affix file
+func splitFlags(flags string, format string) []string {
+	var split []string
+	switch format {
+	case "long":
+		runes := []rune(flags)
+		for i := 0; i+1 < len(runes); i += 2 {
+			split = append(split, string(runes[i:i+2]))
+		}
+	case "num":
+		split = strings.Split(flags, ",")
+	default:
+		for _, flag := range flags {
+			split = append(split, string(flag))
+		}
+	}
+	return split
+}
+
+// Parse a Hunspell affix and dictionary file. Only the affix file settings needed for checking
+// plain words are read: SET, FLAG, TRY, REP, ICONV, ONLYINCOMPOUND, PFX and SFX. Compound words
+// are not built, so COMPOUNDRULE and the other COMPOUND settings are ignored and words marked
+// ONLYINCOMPOUND are left out; add compounds the article uses to .spelling.
+func parseHunspell(aff string, dic string) (*Dictionary, error) {
+	dic, err := decodeDictionary(aff, dic)
+	if err != nil {
+		return nil, err
+	}
+	if aff, err = decodeDictionary(aff, aff); err != nil {
+		return nil, err
+	}
+	dictionary := &Dictionary{Words: map[string]bool{}}
+	classes := map[string]*affixClass{}
+	flagFormat := ""
+	onlyInCompound := ""
+	for i, line := range strings.Split(strings.ReplaceAll(aff, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "FLAG":
+			if len(fields) > 1 {
+				flagFormat = fields[1]
+			}
+		case "TRY":
+			if len(fields) > 1 {
+				dictionary.Try = fields[1]
+			}
+		case "REP":
+			if len(fields) >= 3 {
+				dictionary.Replacements = append(dictionary.Replacements, [2]string{strings.ReplaceAll(fields[1], "_", " "), strings.ReplaceAll(fields[2], "_", " ")})
+			}
+		case "ICONV":
+			// The first ICONV line is the number of conversions
+			if len(fields) >= 3 {
+				dictionary.Conversions = append(dictionary.Conversions, [2]string{fields[1], fields[2]})
+			}
+		case "ONLYINCOMPOUND":
+			if len(fields) > 1 {
+				onlyInCompound = fields[1]
+			}
+		case "PFX", "SFX":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("Invalid affix rule on line %d: %v", i+1, line)
+			}
+			class, ok := classes[fields[1]]
+			if !ok {
+				// The first line of a class is its header: flag, cross product and rule count
+				classes[fields[1]] = &affixClass{Prefix: fields[0] == "PFX", Cross: fields[2] == "Y"}
+				continue
+			}
+			rule := affixRule{Strip: fields[2], Add: strings.SplitN(fields[3], "/", 2)[0]}
+			if rule.Strip == "0" {
+				rule.Strip = ""
+			}
+			if rule.Add == "0" {
+				rule.Add = ""
+			}
+			condition := "."
+			if len(fields) > 4 {
+				condition = fields[4]
+			}
+			pattern := `(?:` + condition + `)$`
+			if class.Prefix {
+				pattern = `^(?:` + condition + `)`
+			}
+			var err error
+			if rule.Condition, err = regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("Invalid affix condition on line %d: %v", i+1, err)
+			}
+			class.Rules = append(class.Rules, rule)
+		}
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(dic, "\r\n", "\n"), "\n") {
+		fields := strings.Fields(line)
+		// The first line is the number of entries
+		if len(fields) == 0 || i == 0 && len(fields) == 1 && isNumber(fields[0]) {
+			continue
+		}
+		word, flags, _ := strings.Cut(fields[0], "/")
+		entryFlags := splitFlags(flags, flagFormat)
+		if onlyInCompound != "" && slices.Contains(entryFlags, onlyInCompound) {
+			continue
+		}
+		dictionary.Words[word] = true
+		var prefixes, suffixes []*affixClass
+		for _, flag := range entryFlags {
+			if class, ok := classes[flag]; ok && class.Prefix {
+				prefixes = append(prefixes, class)
+			} else if ok {
+				suffixes = append(suffixes, class)
+			}
+		}
+		forms := []string{word}
+		for _, suffix := range suffixes {
+			for _, form := range applyAffixes(word, suffix) {
+				dictionary.Words[form] = true
+				if suffix.Cross {
+					forms = append(forms, form)
+				}
+			}
+		}
+		for _, prefix := range prefixes {
+			for j, form := range forms {
+				if j > 0 && !prefix.Cross {
+					break
+				}
+				for _, prefixed := range applyAffixes(form, prefix) {
+					dictionary.Words[prefixed] = true
+				}
+			}
+		}
+	}
+	return dictionary, nil
+}
+
+func isNumber(text string) bool {
+	_, err := strconv.Atoi(text)
+	return err == nil
+}
+
+// Every form of a word made by the rules of an affix class whose condition it meets
+func applyAffixes(word string, class *affixClass) []string {
+	var forms []string
+	for _, rule := range class.Rules {
+		if !rule.Condition.MatchString(word) {
+			continue
+		}
+		if class.Prefix && strings.HasPrefix(word, rule.Strip) {
+			forms = append(forms, rule.Add+strings.TrimPrefix(word, rule.Strip))
+		} else if !class.Prefix && strings.HasSuffix(word, rule.Strip) {
+			forms = append(forms, strings.TrimSuffix(word, rule.Strip)+rule.Add)
+		}
+	}
+	return forms
+}
+
+// Add the words of a custom word list, one per line with # comments
+func (dictionary *Dictionary) addWords(list string) {
+	for _, line := range strings.Split(list, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if word := strings.TrimSpace(line); word != "" {
+			dictionary.Words[word] = true
+		}
+	}
+}
+
+// Whether a word is spelled correctly. Capitalized words are also accepted in lower case,
+// and possessives when the word they belong to is known.
+func (dictionary *Dictionary) check(word string) bool {
+	word = strings.ReplaceAll(word, "’", "'")
+	for _, conversion := range dictionary.Conversions {
+		word = strings.ReplaceAll(word, conversion[0], conversion[1])
+	}
+	if dictionary.Words[word] {
+		return true
+	}
+	first, size := utf8.DecodeRuneInString(word)
+	if unicode.IsUpper(first) && dictionary.Words[string(unicode.ToLower(first))+word[size:]] {
+		return true
+	}
+	if stem, ok := strings.CutSuffix(word, "'s"); ok {
+		return dictionary.check(stem)
+	}
+	return false
+}
+
+// Suggest known words one edit away from a misspelling, trying the dictionary's common
+// replacements first, then swapped, replaced, missing and extra characters
+func (dictionary *Dictionary) suggest(word string) []string {
+	runes := []rune(word)
+	var candidates []string
+	for _, replacement := range dictionary.Replacements {
+		from, to := replacement[0], replacement[1]
+		for offset := 0; offset < len(word); {
+			i := strings.Index(word[offset:], from)
+			if i < 0 {
+				break
+			}
+			i += offset
+			candidates = append(candidates, word[:i]+to+word[i+len(from):])
+			offset = i + 1
+		}
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		swapped := append([]rune{}, runes...)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		candidates = append(candidates, string(swapped))
+	}
+	for i := range runes {
+		for _, r := range dictionary.Try {
+			if r != runes[i] {
+				candidates = append(candidates, string(runes[:i])+string(r)+string(runes[i+1:]))
+			}
+		}
+	}
+	for i := range runes {
+		candidates = append(candidates, string(runes[:i])+string(runes[i+1:]))
+	}
+	for i := 0; i <= len(runes); i++ {
+		for _, r := range dictionary.Try {
+			candidates = append(candidates, string(runes[:i])+string(r)+string(runes[i:]))
+		}
+	}
+
+	var suggestions []string
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if seen[candidate] || !dictionary.check(candidate) {
+			continue
+		}
+		seen[candidate] = true
+		suggestions = append(suggestions, candidate)
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
+
+// Load a dictionary by name from the repository's dictionaries folder, or otherwise from the
+// bundled dictionaries, and add the repository's custom word list
+func loadDictionary(root string, name string) (*Dictionary, error) {
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("Invalid dictionary name %q", name)
+	}
+	aff, affErr := os.ReadFile(filepath.Join(root, dictionariesFolder, name+".aff"))
+	dic, dicErr := os.ReadFile(filepath.Join(root, dictionariesFolder, name+".dic"))
+	if affErr != nil || dicErr != nil {
+		var err error
+		aff, err = bundledDictionaries.ReadFile(dictionariesFolder + "/" + name + ".aff")
+		if err != nil {
+			return nil, fmt.Errorf("Dictionary %v not found in %v or the bundled dictionaries", name, dictionariesFolder)
+		}
+		if dic, err = bundledDictionaries.ReadFile(dictionariesFolder + "/" + name + ".dic"); err != nil {
+			return nil, fmt.Errorf("Dictionary %v not found in %v or the bundled dictionaries", name, dictionariesFolder)
+		}
+	}
+	dictionary, err := parseHunspell(string(aff), string(dic))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading dictionary %v: %v", name, err)
+	}
+	words, err := os.ReadFile(filepath.Join(root, customWordsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Error reading %v: %v", customWordsFile, err)
+	}
+	dictionary.addWords(string(words))
+	return dictionary, nil
+}
+
+var (
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}_'’]+`)
+	// Link targets, and paths, hostnames and emails written without a link
+	linkTargetPattern = regexp.MustCompile(`\]\([^)]*\)`)
+	pathLikePattern   = regexp.MustCompile(`\S*\w[./\\@]\w\S*`)
+)
+
+// Whether a word is left unchecked: single letters, acronyms, and words with digits,
+// underscores or inner capitals, which are usually names from code
+func skipSpelling(word string) bool {
+	if utf8.RuneCountInString(word) <= 1 || strings.ContainsFunc(word, func(r rune) bool { return unicode.IsDigit(r) || r == '_' }) {
+		return true
+	}
+	_, size := utf8.DecodeRuneInString(word)
+	return strings.ToUpper(word) == word || strings.ContainsFunc(word[size:], unicode.IsUpper)
+}
+
+// Report unknown words in the content's prose, leaving out code, urls, link targets, html
+// tags and citations. Each word is reported once per line.
+func spellcheck(content string, dictionary *Dictionary, severity string) []Diagnostic {
+	var diagnostics []Diagnostic
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] {
+			continue
+		}
+		for _, pattern := range []*regexp.Regexp{inlineCodePattern, htmlCommentPattern, bareURLPattern, linkTargetPattern, htmlTagPattern, citationPattern, pathLikePattern} {
+			line = pattern.ReplaceAllString(line, " ")
+		}
+		reported := map[string]bool{}
+		for _, word := range wordPattern.FindAllString(line, -1) {
+			word = strings.Trim(word, "'’")
+			if reported[word] || skipSpelling(word) || dictionary.check(word) {
+				continue
+			}
+			reported[word] = true
+			message := fmt.Sprintf("spelling: Unknown word %q", word)
+			if suggestions := dictionary.suggest(word); len(suggestions) > 0 {
+				message += ", did you mean " + quoteAlternatives(suggestions) + "?"
+			}
+			diagnostics = append(diagnostics, Diagnostic{Severity: severity, Line: i + 1, Message: message})
+		}
+	}
+	return diagnostics
+}
+
+// Quote words as "a", "b" or "c"
+func quoteAlternatives(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = strconv.Quote(word)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// Spell check an article's content when SPELLCHECK is warn or block. Misspellings are warnings
+// in warn mode and stop the upload in block mode. SPELLCHECK_DICTIONARY picks the dictionary.
+func spellcheckArticle(content string, articleFile string) ([]Diagnostic, error) {
+	var severity string
+	switch mode := os.Getenv("SPELLCHECK"); mode {
+	case "", severityOff:
+		return nil, nil
+	case spellcheckWarn:
+		severity = severityWarning
+	case spellcheckBlock:
+		severity = severityError
+	default:
+		return nil, fmt.Errorf("Invalid SPELLCHECK %q, expected warn, block or off", mode)
+	}
+	name := os.Getenv("SPELLCHECK_DICTIONARY")
+	if name == "" {
+		name = defaultDictionary
+	}
+	dictionary, err := cachedDictionary(repoRoot(filepath.Dir(articleFile)), name)
+	if err != nil {
+		return nil, err
+	}
+	return spellcheck(content, dictionary, severity), nil
+}
+
+// Dictionaries loaded during this run by repository root and name, which are read once for
+// every article and translation
+var loadedDictionaries = map[[2]string]*Dictionary{}
+
+func cachedDictionary(root string, name string) (*Dictionary, error) {
+	if dictionary, ok := loadedDictionaries[[2]string{root, name}]; ok {
+		return dictionary, nil
+	}
+	dictionary, err := loadDictionary(root, name)
+	if err != nil {
+		return nil, err
+	}
+	loadedDictionaries[[2]string{root, name}] = dictionary
+	return dictionary, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAffix = `TRY esianrtolcdug
+REP 1
+REP f ph
+
+SFX S Y 2
+SFX S   y     ies        [^aeiou]y
+SFX S   0     s          [^y]
+
+SFX G Y 2
+SFX G   e     ing        e
+SFX G   0     ing        [^e]
+
+PFX U Y 1
+PFX U   0     un         .
+`
+
+const testDictionary = `5
+the
+library/S
+write/G
+load/UG
+graph/S
+`
+
+func TestParseHunspell(t *testing.T) {
+	dictionary, err := parseHunspell(testAffix, testDictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range []string{"the", "libraries", "writing", "loading", "unload", "unloading", "graphs", "The", "library's"} {
+		if !dictionary.check(word) {
+			t.Errorf("check(%q) = false, but need true", word)
+		}
+	}
+	for _, word := range []string{"librarys", "writeing", "unthe", "THE", "teh"} {
+		if dictionary.check(word) {
+			t.Errorf("check(%q) = true, but need false", word)
+		}
+	}
+}
+
+func TestParseHunspellSettings(t *testing.T) {
+	// A Latin-1 dictionary with input conversions and a word that only appears in compounds
+	aff := "SET ISO8859-1\nICONV 1\nICONV oe \xf6\nONLYINCOMPOUND c\nCOMPOUNDRULE 1\nCOMPOUNDRULE c*\n"
+	dic := "3\ncaf\xe9\nsch\xf6n\nfoo/c\n"
+	dictionary, err := parseHunspell(aff, dic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dictionary.check("café") || !dictionary.check("schoen") || dictionary.check("foo") {
+		t.Fatalf(`parseHunspell words = %v, but need café and schön without the compound only foo`, dictionary.Words)
+	}
+	if _, err := parseHunspell("SET KOI8-R\n", "1\nword\n"); err == nil {
+		t.Fatal("parseHunspell succeeded, but need an error for an unsupported encoding")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	dictionary, err := parseHunspell(testAffix, testDictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{"teh": "the", "grafs": "graphs", "loadng": "loading", "librarie": "libraries"}
+	for word, want := range cases {
+		if got := strings.Join(dictionary.suggest(word), ", "); got != want {
+			t.Errorf("suggest(%q) = %q, but need %q", word, got, want)
+		}
+	}
+}
+
+func TestSpellcheck(t *testing.T) {
+	dictionary, err := parseHunspell(testAffix, testDictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dictionary.addWords("# Names\nKubernetes\n\nand\nfrom\nor\nare\nnot\n")
+	content := strings.Join([]string{
+		"Teh library, teh graphs and Kubernetes.",
+		"",
+		"```go",
+		"fmt.Println(\"wrod\")",
+		"```",
+		"Load `configWrod` from [the docs](https://example.com/wrod) or https://example.com/speling.",
+		"The parseHunspell, API, v2 and libary.go names are not words.",
+	}, "\n")
+
+	diagnostics := spellcheck(content, dictionary, severityWarning)
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.Message)
+	}
+	want := []string{`spelling: Unknown word "Teh", did you mean "The"?`, `spelling: Unknown word "teh", did you mean "the"?`,
+		`spelling: Unknown word "docs"`, `spelling: Unknown word "names"`, `spelling: Unknown word "words"`}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("spellcheck found\n%v\nbut need\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if diagnostics[0].Line != 1 || diagnostics[2].Line != 6 || diagnostics[0].Severity != severityWarning {
+		t.Fatalf("spellcheck = %v, but need warnings on lines 1 and 6", diagnostics)
+	}
+}
+
+func TestSpellcheckArticle(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	articleFile := filepath.Join(root, "articles", "Setup", "Setup.md")
+	writeTestFile(t, filepath.Join(root, customWordsFile), "uploader\n")
+	content := "The uploader can load the articles in teh repository."
+
+	t.Setenv("SPELLCHECK", "")
+	if diagnostics, err := spellcheckArticle(content, articleFile); err != nil || diagnostics != nil {
+		t.Fatalf("spellcheckArticle = %v, %v, but need nothing when spell checking is off", diagnostics, err)
+	}
+
+	t.Setenv("SPELLCHECK", spellcheckBlock)
+	diagnostics, err := spellcheckArticle(content, articleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Severity != severityError || !strings.Contains(diagnostics[0].Message, `"teh", did you mean "the"`) {
+		t.Fatalf(`spellcheckArticle = %v, but need one error for "teh" with the bundled dictionary`, diagnostics)
+	}
+
+	// A dictionary in the repository replaces the bundled one on the next run
+	loadedDictionaries = map[[2]string]*Dictionary{}
+	writeTestFile(t, filepath.Join(root, dictionariesFolder, "en_US.aff"), testAffix)
+	writeTestFile(t, filepath.Join(root, dictionariesFolder, "en_US.dic"), testDictionary)
+	if diagnostics, err = spellcheckArticle("The uploader can load", articleFile); err != nil || len(diagnostics) != 1 {
+		t.Fatalf(`spellcheckArticle = %v, %v, but need "can" reported with the repository dictionary`, diagnostics, err)
+	}
+
+	t.Setenv("SPELLCHECK_DICTIONARY", "de_DE")
+	if _, err := spellcheckArticle(content, articleFile); err == nil {
+		t.Fatal("spellcheckArticle succeeded, but need an error for a missing dictionary")
+	}
+	t.Setenv("SPELLCHECK", "strict")
+	if _, err := spellcheckArticle(content, articleFile); err == nil {
+		t.Fatal("spellcheckArticle succeeded, but need an error for an unknown mode")
+	}
+}
+
+func TestBundledDictionaryProse(t *testing.T) {
+	dictionary, err := loadDictionary(t.TempDir(), "en_US")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join([]string{
+		"Last summer we moved our team's blog to a static site, and the process taught us a lot about keeping things simple.",
+		"Each time someone pushed a commit, the build downloaded every image again and rewrote pages that had not changed.",
+		"After a few weeks of waiting, we decided to measure where the time actually went. Most of it was spent resizing photos that nobody had touched.",
+		"",
+		"My grandmother grew up in a village by the sea, where her father repaired fishing boats. She often told us stories about the winters there,",
+		"when storms kept everyone indoors for days and the children invented games to pass the time.",
+		"",
+		"Before we begin, make sure you've installed the latest version of the compiler. Errors deserve special attention: if the database is unavailable,",
+		"the server should respond with a clear message instead of crashing. Thanks for reading, and feel free to send us your questions or suggestions.",
+	}, "\n")
+	if diagnostics := spellcheck(content, dictionary, severityWarning); len(diagnostics) != 0 {
+		t.Fatalf("spellcheck found %v in ordinary prose, but need nothing with the bundled dictionary", diagnostics)
+	}
+}
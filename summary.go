@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	wordsPerMinute   = 200
+	maxExcerptLength = 300
+	moreMarker       = "<!--more-->"
+)
+
+var (
+	fencedCodePattern  = regexp.MustCompile("(?ms)^[ \t]*(```|~~~).*?^[ \t]*(```|~~~)[ \t]*$")
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	imagePattern       = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	linkPattern        = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	htmlTagPattern     = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	inlineCodePattern  = regexp.MustCompile("`([^`]*)`")
+	emphasisPattern    = regexp.MustCompile(`(\*\*|\*|~~)([^*~]+)(\*\*|\*|~~)`)
+	underscorePattern  = regexp.MustCompile(`(^|\s)(__|_)([^_]+)(__|_)`)
+	blockPrefixPattern = regexp.MustCompile(`(?m)^[ \t]*(#{1,6}[ \t]+|>[ \t]?|[-*+][ \t]+|\d+\.[ \t]+)`)
+	sentenceEndPattern = regexp.MustCompile(`[.!?]["')\]]?(\s|$)`)
+)
+
+// Reduce markdown to the plain text a reader sees, dropping code blocks and images
+func stripMarkdown(content string) string {
+	text := fencedCodePattern.ReplaceAllString(content, "")
+	text = htmlCommentPattern.ReplaceAllString(text, "")
+	text = imagePattern.ReplaceAllString(text, "")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = inlineCodePattern.ReplaceAllString(text, "$1")
+	text = emphasisPattern.ReplaceAllString(text, "$2")
+	text = underscorePattern.ReplaceAllString(text, "$1$3")
+	text = blockPrefixPattern.ReplaceAllString(text, "")
+	return text
+}
+
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// Estimated reading time in whole minutes, at least one minute
+func readingTime(wordCount int) int {
+	return max(1, int(math.Ceil(float64(wordCount)/wordsPerMinute)))
+}
+
+// The text before a <!--more--> marker, or otherwise the first paragraph of prose,
+// with markdown stripped and truncated at a sentence boundary.
+func generateExcerpt(content string) string {
+	var excerpt string
+	if before, _, found := strings.Cut(content, moreMarker); found {
+		excerpt = strings.TrimSpace(stripMarkdown(before))
+	} else {
+		body := fencedCodePattern.ReplaceAllString(content, "")
+		for _, paragraph := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+			trimmed := strings.TrimSpace(paragraph)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if text := strings.TrimSpace(stripMarkdown(trimmed)); text != "" {
+				excerpt = text
+				break
+			}
+		}
+	}
+	excerpt = strings.Join(strings.Fields(excerpt), " ")
+	return truncateAtSentence(excerpt, maxExcerptLength)
+}
+
+// Cut text to at most limit characters, ending on a full sentence where possible
+func truncateAtSentence(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit])
+	ends := sentenceEndPattern.FindAllStringIndex(cut, -1)
+	if len(ends) > 0 {
+		return strings.TrimSpace(cut[:ends[len(ends)-1][1]])
+	}
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
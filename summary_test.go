@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	content := "## Setup\n\nInstall **Go** from [the site](https://go.dev) and run `go version`.\n\n```go\nfmt.Println(\"hi\")\n```\n\n![diagram](diagram)\n- keep snake_case_names"
+	want := "Setup\n\nInstall Go from the site and run go version.\n\n\n\n\nkeep snake_case_names"
+	if got := stripMarkdown(content); got != want {
+		t.Fatalf(`stripMarkdown = %q, but need %q`, got, want)
+	}
+}
+
+func TestGenerateExcerpt(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"# Title\n\nFirst *paragraph* here.\n\nSecond paragraph.", "First paragraph here."},
+		{"Intro text.\n\nMore intro.\n<!--more-->\nRest of the article.", "Intro text. More intro."},
+		{"```sh\necho skipped\n```\n\nActual prose.", "Actual prose."},
+	}
+	for _, test := range tests {
+		if got := generateExcerpt(test.content); got != test.want {
+			t.Errorf(`generateExcerpt(%q) = %q, but need %q`, test.content, got, test.want)
+		}
+	}
+
+	long := strings.Repeat("This sentence is exactly forty chars ok. ", 10)
+	got := generateExcerpt(long)
+	if len(got) > maxExcerptLength || !strings.HasSuffix(got, ".") {
+		t.Fatalf(`generateExcerpt did not truncate at a sentence boundary: %q`, got)
+	}
+	if got := truncateAtSentence(strings.Repeat("word ", 100), 50); !strings.HasSuffix(got, "word…") {
+		t.Fatalf(`truncateAtSentence without sentences = %q, but need a word boundary and ellipsis`, got)
+	}
+}
+
+func TestCreateArticlePayloadSummary(t *testing.T) {
+	articleFile := filepath.Join(t.TempDir(), "post.md")
+	writeTestFile(t, articleFile, "Hello there reader. "+strings.Repeat("word ", 397))
+	article, err := createArticlePayload("post", articleFile, "")
+	if err != nil || article.WordCount != 400 || article.ReadingTime != 2 || article.Excerpt == "" {
+		t.Fatalf(`createArticlePayload summary = %d words, %d minutes, %q, %v`, article.WordCount, article.ReadingTime, article.Excerpt, err)
+	}
+
+	writeTestFile(t, articleFile, "---\nexcerpt: Custom summary\nreading_time: 7\n---\nShort article.")
+	article, err = createArticlePayload("post", articleFile, "")
+	if err != nil || article.WordCount != 2 || article.ReadingTime != 7 || article.Excerpt != "Custom summary" {
+		t.Fatalf(`createArticlePayload front matter summary = %d words, %d minutes, %q, %v`, article.WordCount, article.ReadingTime, article.Excerpt, err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const taxonomyFile = "taxonomy.yaml"
+
+// Taxonomy lists the tags and categories articles are allowed to use. It is read
+// from taxonomy.yaml at the repository root.
+type Taxonomy struct {
+	Tags       []string `yaml:"tags"`
+	Categories []string `yaml:"categories"`
+}
+
+// A Term is a tag or category as stored on the server
+type Term struct {
+	ID   *int   `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// Load the taxonomy, returning nil when the repository does not have one
+func loadTaxonomy(root string) (*Taxonomy, error) {
+	data, err := os.ReadFile(filepath.Join(root, taxonomyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", taxonomyFile, err)
+	}
+	var taxonomy Taxonomy
+	if err := yaml.Unmarshal(data, &taxonomy); err != nil {
+		return nil, fmt.Errorf("Error parsing %v: %v", taxonomyFile, err)
+	}
+	return &taxonomy, nil
+}
+
+// Check an article's tags and category against the taxonomy, suggesting the closest
+// allowed term for anything that is not in it.
+func (taxonomy Taxonomy) validate(tags []string, category string) error {
+	var problems []string
+	for _, tag := range tags {
+		if !containsTerm(taxonomy.Tags, tag) {
+			problems = append(problems, unknownTerm("tag", tag, taxonomy.Tags))
+		}
+	}
+	if category != "" && !containsTerm(taxonomy.Categories, category) {
+		problems = append(problems, unknownTerm("category", category, taxonomy.Categories))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%v", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func containsTerm(terms []string, term string) bool {
+	return slices.ContainsFunc(terms, func(candidate string) bool {
+		return strings.EqualFold(candidate, term)
+	})
+}
+
+func unknownTerm(kind string, term string, allowed []string) string {
+	message := fmt.Sprintf("%v %q is not in %v", kind, term, taxonomyFile)
+	best, bestDistance := "", 3
+	for _, candidate := range allowed {
+		if distance := editDistance(strings.ToLower(term), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best != "" {
+		message += fmt.Sprintf(", did you mean %q?", best)
+	}
+	return message
+}
+
+// Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current := make([]int, len(br)+1)
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(br)]
+}
+
+// Validate the front matter tags and category against the repository taxonomy, if it has one
+func validateTaxonomy(articleFile string, frontMatter FrontMatter) error {
+	taxonomy, err := loadTaxonomy(repoRoot(filepath.Dir(articleFile)))
+	if err != nil || taxonomy == nil {
+		return err
+	}
+	return taxonomy.validate(frontMatter.Tags, frontMatter.Category)
+}
+
+// Resolve term names to server ids by listing the terms at the endpoint held in endpointEnv.
+// Missing terms are created when the variable named by createEnv is true. Each id is returned once.
+func resolveTermIDs(endpointEnv string, createEnv string, names []string) ([]int, error) {
+	ids := []int{}
+	if len(names) == 0 {
+		return ids, nil
+	}
+	url, err := apiURL(endpointEnv)
+	if err != nil {
+		return nil, err
+	}
+	var terms []Term
+	if err := sendAuthorizedJSON(http.MethodGet, url, nil, &terms); err != nil {
+		return nil, fmt.Errorf("Error listing terms: %v", err)
+	}
+
+	for _, name := range names {
+		index := slices.IndexFunc(terms, func(term Term) bool {
+			return strings.EqualFold(term.Name, name) && term.ID != nil
+		})
+		if index != -1 {
+			if !slices.Contains(ids, *terms[index].ID) {
+				ids = append(ids, *terms[index].ID)
+			}
+			continue
+		}
+		if os.Getenv(createEnv) != "true" {
+			return nil, fmt.Errorf("%q does not exist on the server, set %v=true to create it", name, createEnv)
+		}
+		var created Term
+		if err := sendAuthorizedJSON(http.MethodPost, url, Term{Name: name}, &created); err != nil {
+			return nil, fmt.Errorf("Error creating %q: %v", name, err)
+		}
+		if created.ID == nil {
+			return nil, fmt.Errorf("Server did not return an id for created term %q", name)
+		}
+		logger.Info("Created term on the server", "term", name, "id", *created.ID)
+		terms = append(terms, created)
+		ids = append(ids, *created.ID)
+	}
+	return ids, nil
+}
+
+// Fill in the server ids of an article's tags and category. Tags are sent whenever TAG_ENDPOINT
+// is set, so removing every tag from an article clears them on the server.
+func resolveArticleTaxonomy(article *Article) error {
+	if _, ok := os.LookupEnv("TAG_ENDPOINT"); ok || len(article.Tags) > 0 {
+		tagIDs, err := resolveTermIDs("TAG_ENDPOINT", "ALLOW_CREATE_TAGS", article.Tags)
+		if err != nil {
+			return fmt.Errorf("Error resolving tags: %v", err)
+		}
+		article.TagIDs = &tagIDs
+	}
+	if article.Category == "" {
+		return nil
+	}
+	categoryIDs, err := resolveTermIDs("CATEGORY_ENDPOINT", "ALLOW_CREATE_CATEGORIES", []string{article.Category})
+	if err != nil {
+		return fmt.Errorf("Error resolving category: %v", err)
+	}
+	article.CategoryID = &categoryIDs[0]
+	return nil
+}
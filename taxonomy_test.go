@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateTaxonomy(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	writeTestFile(t, filepath.Join(root, "taxonomy.yaml"), "tags: [golang, testing]\ncategories: [Tutorials]\n")
+	articleFile := filepath.Join(root, "intro", "intro.md")
+
+	writeTestFile(t, articleFile, "---\ntags: [Golang, testing]\ncategory: tutorials\n---\nHello")
+	article, err := createArticlePayload("intro", articleFile, "")
+	if err != nil || !reflect.DeepEqual(article.Tags, []string{"Golang", "testing"}) || article.Category != "tutorials" {
+		t.Fatalf(`createArticlePayload = %+v, %v`, article, err)
+	}
+
+	writeTestFile(t, articleFile, "---\ntags: [golnag]\ncategory: news\n---\nHello")
+	_, err = createArticlePayload("intro", articleFile, "")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "golang"`) || !strings.Contains(err.Error(), `category "news"`) {
+		t.Fatalf(`createArticlePayload expected taxonomy errors, got: %v`, err)
+	}
+}
+
+func TestResolveArticleTaxonomy(t *testing.T) {
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("Request to %v was sent without authentication", r.URL.Path)
+		}
+		if r.Method == http.MethodPost {
+			var term Term
+			json.NewDecoder(r.Body).Decode(&term)
+			created = append(created, term.Name)
+			id := 30
+			term.ID = &id
+			json.NewEncoder(w).Encode(term)
+			return
+		}
+		golang, tutorials := 10, 20
+		if strings.Contains(r.URL.Path, "categories") {
+			json.NewEncoder(w).Encode([]Term{{ID: &tutorials, Name: "Tutorials"}})
+			return
+		}
+		json.NewEncoder(w).Encode([]Term{{ID: &golang, Name: "golang"}})
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("TAG_ENDPOINT", "api/tags/")
+	t.Setenv("CATEGORY_ENDPOINT", "api/categories/")
+
+	article := Article{Tags: []string{"golang", "testing"}, Category: "tutorials"}
+	if err := resolveArticleTaxonomy(&article); err == nil {
+		t.Fatalf(`resolveArticleTaxonomy expected an error for a missing tag when creation is not allowed`)
+	}
+
+	t.Setenv("ALLOW_CREATE_TAGS", "true")
+	if err := resolveArticleTaxonomy(&article); err != nil {
+		t.Fatalf(`resolveArticleTaxonomy returned unexpected error: %v`, err)
+	}
+	if article.TagIDs == nil || !reflect.DeepEqual(*article.TagIDs, []int{10, 30}) || article.CategoryID == nil || *article.CategoryID != 20 {
+		t.Fatalf(`resolveArticleTaxonomy = %v, %v, but need [10 30] and 20`, article.TagIDs, article.CategoryID)
+	}
+
+	// Duplicate tags are sent once, and an article without tags clears them
+	article = Article{Tags: []string{"golang", "Golang"}}
+	if err := resolveArticleTaxonomy(&article); err != nil || !reflect.DeepEqual(*article.TagIDs, []int{10}) {
+		t.Fatalf(`resolveArticleTaxonomy = %v, %v, but need [10]`, article.TagIDs, err)
+	}
+	article = Article{}
+	if err := resolveArticleTaxonomy(&article); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := json.Marshal(article); err != nil || !strings.Contains(string(data), `"tag_ids":[]`) {
+		t.Fatalf(`Article without tags = %s, %v, but need "tag_ids":[]`, data, err)
+	}
+	if !reflect.DeepEqual(created, []string{"testing"}) {
+		t.Fatalf(`resolveArticleTaxonomy created %v, but need [testing]`, created)
+	}
+
+	// Categories are only created with their own flag
+	article = Article{Category: "News"}
+	if err := resolveArticleTaxonomy(&article); err == nil || !strings.Contains(err.Error(), "ALLOW_CREATE_CATEGORIES") {
+		t.Fatalf(`resolveArticleTaxonomy = %v, but need an error for a missing category`, err)
+	}
+	t.Setenv("ALLOW_CREATE_CATEGORIES", "true")
+	if err := resolveArticleTaxonomy(&article); err != nil || *article.CategoryID != 30 {
+		t.Fatalf(`resolveArticleTaxonomy = %v, %v, but need the created category`, article.CategoryID, err)
+	}
+}
+
+func TestUploadArticleResolvesTaxonomyFirst(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Unexpected %v request to %v before the tags were resolved", r.Method, r.URL)
+		}
+		json.NewEncoder(w).Encode([]Term{})
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("TAG_ENDPOINT", "api/tags/")
+	t.Setenv("ATTACHMENT_ENDPOINT", "api/attachments/")
+
+	article := Article{Title: "Intro", Tags: []string{"golang"}, Attachments: []Attachment{{Filename: "slides.pdf", Path: "slides.pdf", Checksum: "abc"}}}
+	if _, err := uploadArticle(article); err == nil || !strings.Contains(err.Error(), "ALLOW_CREATE_TAGS") {
+		t.Fatalf(`uploadArticle = %v, but need an error for the missing tag before any upload`, err)
+	}
+}
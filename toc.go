@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// A Heading is an entry in the article's table of contents
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+	Line   int    `json:"-"`
+}
+
+var (
+	atxHeadingPattern    = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	setextHeadingPattern = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	fencePattern         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	// Lines that cannot be the text of a setext heading
+	blockStartPattern = regexp.MustCompile(`^( {4,}|\t| {0,3}([>#|]|[-*+][ \t]|\d+[.)][ \t]|<))`)
+)
+
+// Anchor for a heading in the style GitHub uses: lowercase, punctuation removed and spaces as dashes
+func slugify(text string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			slug.WriteRune(r)
+		case unicode.IsSpace(r):
+			slug.WriteRune('-')
+		}
+	}
+	return slug.String()
+}
+
+// Split markdown into lines, reporting for each whether it is inside a fenced code block.
+// A fence is closed by a fence of the same character that is at least as long.
+func markdownLines(content string) ([]string, []bool) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	inCode := make([]bool, len(lines))
+	fence := ""
+	for i, line := range lines {
+		match := fencePattern.FindStringSubmatch(line)
+		switch {
+		case fence == "" && match != nil && !(match[1][0] == '`' && strings.Contains(match[2], "`")):
+			fence = match[1]
+			inCode[i] = true
+		case fence != "" && match != nil && match[1][0] == fence[0] && len(match[1]) >= len(fence) && strings.TrimSpace(match[2]) == "":
+			fence = ""
+			inCode[i] = true
+		default:
+			inCode[i] = fence != ""
+		}
+	}
+	return lines, inCode
+}
+
+// Extract the ATX and setext headings of an article outside of code blocks. Lines are relative
+// to the content.
+func extractHeadings(content string) []Heading {
+	var headings []Heading
+	anchors := map[string]int{}
+	lines, inCode := markdownLines(content)
+	// Start of the paragraph the current line belongs to, -1 between blocks, or -2 in a block
+	// such as a list or quote that runs to the next blank line
+	paragraph := -1
+	for i, line := range lines {
+		if inCode[i] || strings.TrimSpace(line) == "" {
+			paragraph = -1
+			continue
+		}
+		var level, start int
+		var text string
+		atx := atxHeadingPattern.FindStringSubmatch(line)
+		underline := setextHeadingPattern.FindStringSubmatch(line)
+		switch {
+		case atx != nil:
+			level, start, text = len(atx[1]), i, atx[2]
+			paragraph = -1
+		case underline != nil && paragraph >= 0:
+			// The underline turns the paragraph above it into a heading
+			level, start = 2, paragraph
+			if underline[1][0] == '=' {
+				level = 1
+			}
+			text = strings.Join(lines[paragraph:i], " ")
+			paragraph = -1
+		case underline != nil:
+			// A thematic break
+			continue
+		case paragraph == -1 && blockStartPattern.MatchString(line):
+			paragraph = -2
+			continue
+		case paragraph == -1:
+			paragraph = i
+			continue
+		default:
+			continue
+		}
+		text = strings.TrimSpace(stripMarkdown(text))
+		anchor := slugify(text)
+		// Repeated headings get numbered anchors, matching how the headings are rendered
+		if count := anchors[anchor]; count > 0 {
+			anchors[anchor] = count + 1
+			anchor = fmt.Sprintf("%v-%d", anchor, count)
+		} else {
+			anchors[anchor] = 1
+		}
+		headings = append(headings, Heading{Level: level, Text: text, Anchor: anchor, Line: start + 1})
+	}
+	return headings
+}
+
+// Warn about more than one H1 and about headings that skip a level
+func validateHeadings(headings []Heading) []Diagnostic {
+	var diagnostics []Diagnostic
+	previous := 0
+	h1s := 0
+	for _, heading := range headings {
+		if heading.Level == 1 {
+			h1s++
+			if h1s == 2 {
+				diagnostics = append(diagnostics, Diagnostic{Severity: severityWarning, Line: heading.Line,
+					Message: fmt.Sprintf("Article has more than one H1 heading: %q", heading.Text)})
+			}
+		}
+		if previous > 0 && heading.Level > previous+1 {
+			diagnostics = append(diagnostics, Diagnostic{Severity: severityWarning, Line: heading.Line,
+				Message: fmt.Sprintf("Heading %q skips from H%d to H%d", heading.Text, previous, heading.Level)})
+		}
+		previous = heading.Level
+	}
+	return diagnostics
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExtractHeadings(t *testing.T) {
+	content := "# Intro to *Go*\n\nText\n\n## Setup & Install ##\n\n```sh\n# not a heading\n```\n\n## Setup & Install\n### What's `go.mod`?"
+	want := []Heading{
+		{Level: 1, Text: "Intro to Go", Anchor: "intro-to-go", Line: 1},
+		{Level: 2, Text: "Setup & Install", Anchor: "setup--install", Line: 5},
+		{Level: 2, Text: "Setup & Install", Anchor: "setup--install-1", Line: 11},
+		{Level: 3, Text: "What's go.mod?", Anchor: "whats-gomod", Line: 12},
+	}
+	if got := extractHeadings(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf(`extractHeadings = %+v, but need %+v`, got, want)
+	}
+}
+
+func TestCreateArticlePayloadHeadingWarnings(t *testing.T) {
+	articleFile := filepath.Join(t.TempDir(), "post.md")
+	writeTestFile(t, articleFile, "---\ntitle: Post\n---\n\n# Post\n\n### Skipped\n\n# Another H1\n")
+	article, err := createArticlePayload("post", articleFile, "")
+	if err != nil {
+		t.Fatalf(`createArticlePayload returned unexpected error: %v`, err)
+	}
+	if len(article.TOC) != 3 {
+		t.Fatalf(`createArticlePayload toc = %+v, but need 3 headings`, article.TOC)
+	}
+	want := []Diagnostic{
+		{Severity: severityWarning, Message: `Heading "Skipped" skips from H1 to H3`, File: articleFile, Line: 7},
+		{Severity: severityWarning, Message: `Article has more than one H1 heading: "Another H1"`, File: articleFile, Line: 9},
+	}
+	if !reflect.DeepEqual(article.Diagnostics, want) {
+		t.Fatalf(`createArticlePayload diagnostics = %v, but need %v`, article.Diagnostics, want)
+	}
+	if hasErrors(article.Diagnostics) {
+		t.Fatalf(`heading problems should only be warnings`)
+	}
+}
+
+func TestExtractHeadingsFencesAndSetext(t *testing.T) {
+	content := "Intro to Go\n===========\n\n````md\n```\n# not a heading\n```\n````\n\nSetup\nand install\n---\n\n- item\n---\n\n~~~\n# not a heading\n```\n~~~"
+	want := []Heading{
+		{Level: 1, Text: "Intro to Go", Anchor: "intro-to-go", Line: 1},
+		{Level: 2, Text: "Setup and install", Anchor: "setup-and-install", Line: 10},
+	}
+	if got := extractHeadings(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf(`extractHeadings = %+v, but need %+v`, got, want)
+	}
+}
+
+func TestCreateArticlePayloadDiagnosticsAfterExpansion(t *testing.T) {
+	folder := t.TempDir()
+	writeTestFile(t, filepath.Join(folder, "article.yaml"), "extra_markdown: include\n")
+	articleFile := filepath.Join(folder, "index.md")
+	writeTestFile(t, articleFile, "---\ntitle: Post\n---\n\n# Post\n\n> [!NOTE]\n> Remember this\n\n### Skipped\n")
+	writeTestFile(t, filepath.Join(folder, "appendix.md"), "---\ntitle: Appendix\n---\n\n# Appendix\n")
+
+	article, err := createArticlePayload("post", articleFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Diagnostic{
+		{Severity: severityWarning, Message: `Heading "Skipped" skips from H1 to H3`, File: articleFile, Line: 10},
+		{Severity: severityWarning, Message: `Article has more than one H1 heading: "Appendix"`, File: filepath.Join(folder, "appendix.md"), Line: 5},
+	}
+	if !reflect.DeepEqual(article.Diagnostics, want) {
+		t.Fatalf(`createArticlePayload diagnostics = %v, but need %v`, article.Diagnostics, want)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Matches language variants such as article.en.md or article.pt-BR.md
+var languageSuffixPattern = regexp.MustCompile(`^(.+)\.([a-z]{2,3}(?:-[A-Za-z]{2})?)\.md$`)
+
+// ISO 639-1 language codes accepted as filename suffixes, so post.go.md is not a translation
+var knownLanguages = strings.Fields(`
+	aa ab ae af ak am an ar as av ay az ba be bg bi bm bn bo br bs ca ce ch co cr cs cu cv cy
+	da de dv dz ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv ha he hi ho hr ht
+	hu hy hz ia id ie ig ii ik io is it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky
+	la lb lg li ln lo lt lu lv mg mh mi mk ml mn mr ms mt my na nb nd ne ng nl nn no nr nv ny
+	oc oj om or os pa pi pl ps pt qu rm rn ro ru rw sa sc sd se sg si sk sl sm sn so sq sr ss
+	st su sv sw ta te tg th ti tk tl tn to tr ts tt tw ty ug uk ur uz ve vi vo wa wo xh yi yo
+	za zh zu`)
+
+// Name and language of a markdown file with a language suffix. The suffix must be a known
+// language code or the configured default language, otherwise the language is empty.
+func languageSuffix(articleFolder string, file string) (string, string) {
+	match := languageSuffixPattern.FindStringSubmatch(filepath.Base(file))
+	if match == nil {
+		return "", ""
+	}
+	language := match[2]
+	code, _, _ := strings.Cut(language, "-")
+	if slices.Contains(knownLanguages, code) {
+		return match[1], language
+	}
+	config, err := loadFolderConfig(articleFolder)
+	if err == nil && language == defaultLanguage(config) {
+		return match[1], language
+	}
+	return "", ""
+}
+
+// Language used for the primary article of a translated folder. Set with
+// `default_language` in article.yaml or the DEFAULT_LANGUAGE env variable.
+func defaultLanguage(config FolderConfig) string {
+	if config.DefaultLanguage != "" {
+		return config.DefaultLanguage
+	}
+	if language := os.Getenv("DEFAULT_LANGUAGE"); language != "" {
+		return language
+	}
+	return "en"
+}
+
+// Name of the article a markdown file holds, without its extension or language suffix
+func markdownName(articleFolder string, file string) (string, error) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if suffixName, language := languageSuffix(articleFolder, file); language != "" {
+		name = suffixName
+	}
+	if name == "index" {
+		// index.md articles take their name from the folder they live in
+		absFolder, err := filepath.Abs(articleFolder)
+		if err != nil {
+			return "", err
+		}
+		name = filepath.Base(absFolder)
+	}
+	return name, nil
+}
+
+// Language of a markdown file, taken from its filename suffix or its `lang` front matter
+func markdownLanguage(file string) (string, error) {
+	if _, language := languageSuffix(filepath.Dir(file), file); language != "" {
+		return language, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("Error reading file: %v", err)
+	}
+	frontMatter, _, err := parseFrontMatter(string(data))
+	if err != nil {
+		return "", fmt.Errorf("Error reading front matter of %v: %v", file, err)
+	}
+	return frontMatter.Lang, nil
+}
+
+// Map each language to its markdown file for the files in the folder that declare a language
+func languageVariants(articleFolder string, markdownFiles []string) (map[string]string, error) {
+	variants := map[string]string{}
+	for _, file := range markdownFiles {
+		language, err := markdownLanguage(filepath.Join(articleFolder, file))
+		if err != nil {
+			return nil, err
+		}
+		if language == "" {
+			continue
+		}
+		if existing, ok := variants[language]; ok {
+			return nil, fmt.Errorf("Files %v and %v are both written in language %v", existing, file, language)
+		}
+		variants[language] = file
+	}
+	return variants, nil
+}
+
+// When every markdown file in a folder is a language variant they are translations of a
+// single article, and the default language variant (or the first by language) is published first.
+func selectPrimaryTranslation(articleFolder string, markdownFiles []string, config FolderConfig) (string, bool, error) {
+	if len(markdownFiles) < 2 {
+		return "", false, nil
+	}
+	variants, err := languageVariants(articleFolder, markdownFiles)
+	if err != nil {
+		return "", false, err
+	}
+	if len(variants) != len(markdownFiles) {
+		return "", false, nil
+	}
+	if file, ok := variants[defaultLanguage(config)]; ok {
+		return file, true, nil
+	}
+	languages := slices.Sorted(maps.Keys(variants))
+	return variants[languages[0]], true, nil
+}
+
+// Find the translations of the main article file, ordered by language
+func findTranslations(articleFolder string, articleFile string) ([]string, error) {
+	mainLanguage, err := markdownLanguage(articleFile)
+	if err != nil || mainLanguage == "" {
+		return nil, err
+	}
+	files, err := os.ReadDir(articleFolder)
+	if err != nil {
+		return nil, err
+	}
+	articleName, err := markdownName(articleFolder, articleFile)
+	if err != nil {
+		return nil, err
+	}
+	var markdownFiles []string
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".md" {
+			continue
+		}
+		// Files with a language suffix are translations only when they share the article's
+		// name, so appendix.de.md is the German appendix rather than a translation
+		if _, language := languageSuffix(articleFolder, file.Name()); language != "" {
+			if name, err := markdownName(articleFolder, file.Name()); err != nil || name != articleName {
+				continue
+			}
+		}
+		markdownFiles = append(markdownFiles, file.Name())
+	}
+	variants, err := languageVariants(articleFolder, markdownFiles)
+	if err != nil {
+		return nil, err
+	}
+	var languages []string
+	for language, file := range variants {
+		if file != filepath.Base(articleFile) {
+			languages = append(languages, language)
+		}
+	}
+	slices.Sort(languages)
+	var translations []string
+	for _, language := range languages {
+		translations = append(translations, filepath.Join(articleFolder, variants[language]))
+	}
+	return translations, nil
+}
+
+// Articles without a language are in the default language of the local article's folder
+func sameLanguage(local Article, server Article) bool {
+	if local.Language == server.Language {
+		return true
+	}
+	fallback := local.DefaultLanguage
+	if fallback == "" {
+		fallback = defaultLanguage(FolderConfig{})
+	}
+	return (local.Language == "" && server.Language == fallback) || (server.Language == "" && local.Language == fallback)
+}
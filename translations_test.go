@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArticleTranslations(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "intro_to_go.de.md"), "Hallo")
+	writeTestFile(t, filepath.Join(articleFolder, "intro_to_go.en.md"), "Hello")
+	writeTestFile(t, filepath.Join(articleFolder, "intro_to_go.fr.md"), "Bonjour")
+
+	name, articleFile, _, err := parseArticle(articleFolder)
+	if err != nil || name != "intro_to_go" || articleFile != filepath.Join(articleFolder, "intro_to_go.en.md") {
+		t.Fatalf(`parseArticle(%v) = %q, %q, %v, but need "intro_to_go", intro_to_go.en.md`, articleFolder, name, articleFile, err)
+	}
+	translations, err := findTranslations(articleFolder, articleFile)
+	want := []string{filepath.Join(articleFolder, "intro_to_go.de.md"), filepath.Join(articleFolder, "intro_to_go.fr.md")}
+	if err != nil || !reflect.DeepEqual(translations, want) {
+		t.Fatalf(`findTranslations = %v, %v, but need %v`, translations, err, want)
+	}
+	if extras, err := findExtraMarkdown(articleFolder, articleFile); err != nil || len(extras) != 0 {
+		t.Fatalf(`findExtraMarkdown = %v, %v, translations should not be extra markdown`, extras, err)
+	}
+}
+
+func TestParseArticleFrontMatterLanguages(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "default_language: de\n")
+	writeTestFile(t, filepath.Join(articleFolder, "post.md"), "---\nlang: en\n---\nHello")
+	writeTestFile(t, filepath.Join(articleFolder, "beitrag.md"), "---\nlang: de\ntitle: Einführung\n---\nHallo")
+
+	name, articleFile, photos, err := parseArticle(articleFolder)
+	if err != nil || articleFile != filepath.Join(articleFolder, "beitrag.md") {
+		t.Fatalf(`parseArticle(%v) = %q, %v, but need beitrag.md`, articleFolder, articleFile, err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil || article.Language != "de" || article.Title != "Einführung" || article.Content != "Hallo" {
+		t.Fatalf(`createArticlePayload = %+v, %v`, article, err)
+	}
+}
+
+func TestParseArticleDuplicateLanguage(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "post.en.md"), "Hello")
+	writeTestFile(t, filepath.Join(articleFolder, "other.md"), "---\nlang: en\n---\nHello again")
+
+	if _, articleFile, _, err := parseArticle(articleFolder); err == nil {
+		t.Fatalf(`parseArticle(%v) = %q, expected an error for two files in the same language`, articleFolder, articleFile)
+	}
+}
+
+func TestCheckIfArticleExistsMatchesLanguage(t *testing.T) {
+	en, de := 1, 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Article{
+			{ID: &en, Title: "Intro to Go"},
+			{ID: &de, Title: "Intro to Go", Language: "de"},
+		})
+	}))
+	defer server.Close()
+	useTestServer(t, server)
+	t.Setenv("GET_ENDPOINT", "api/articles/")
+
+	for language, want := range map[string]int{"en": en, "de": de, "": en} {
+		existing, err := checkIfArticleExists(Article{Title: "Intro to Go", Language: language})
+		if err != nil || existing == nil || *existing.ID != want {
+			t.Fatalf(`checkIfArticleExists(%q) = %v, %v, but need article %d`, language, existing, err, want)
+		}
+	}
+	if existing, err := checkIfArticleExists(Article{Title: "Intro to Go", Language: "fr"}); err != nil || existing != nil {
+		t.Fatalf(`checkIfArticleExists("fr") = %v, %v, but need no match`, existing, err)
+	}
+}
+
+func TestLanguageSuffixKnownCodes(t *testing.T) {
+	articleFolder := t.TempDir()
+	writeTestFile(t, filepath.Join(articleFolder, "article.yaml"), "default_language: tlh\n")
+	cases := map[string]string{"post.de.md": "de", "post.pt-BR.md": "pt-BR", "post.go.md": "", "intro.js.md": "", "post.tlh.md": "tlh", "post.md": ""}
+	for file, want := range cases {
+		if _, language := languageSuffix(articleFolder, file); language != want {
+			t.Errorf(`languageSuffix(%v) = %q, but need %q`, file, language, want)
+		}
+	}
+}
+
+func TestSameLanguageUsesFolderDefault(t *testing.T) {
+	local := Article{Title: "Einführung", DefaultLanguage: "de"}
+	if !sameLanguage(local, Article{Language: "de"}) || sameLanguage(local, Article{Language: "en"}) {
+		t.Fatal(`sameLanguage should treat an article without a language as its folder's default language "de"`)
+	}
+}
+
+func TestCreateTranslationPayloadSharesPhotos(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	t.Setenv("GENERATE_COVER", "true")
+	articleFolder := filepath.Join(root, "harbour")
+	photos := filepath.Join(articleFolder, "photos")
+	writeTestFile(t, filepath.Join(photos, "boats.png"), "")
+	writeTestFile(t, filepath.Join(photos, captionsFile), "boats: {alt: Boote}\n")
+	writeTestFile(t, filepath.Join(articleFolder, "harbour.en.md"), "Hello\n\n{{< gallery >}}")
+	writeTestFile(t, filepath.Join(articleFolder, "harbour.de.md"), "Hallo\n\n{{< gallery >}}")
+
+	translation, err := createTranslationPayload("harbour", filepath.Join(articleFolder, "harbour.de.md"), photos, Article{CoverImage: "cover"})
+	if err != nil || hasErrors(translation.Diagnostics) {
+		t.Fatalf(`createTranslationPayload = %v, %v, but need the gallery to use the shared photos`, translation.Diagnostics, err)
+	}
+	if !strings.Contains(translation.Content, "![Boote](boats)") || translation.CoverImage != "cover" || len(translation.Images) != 0 {
+		t.Fatalf(`createTranslationPayload = %q with cover %q and images %v, but need the shared photos and cover`, translation.Content, translation.CoverImage, translation.Images)
+	}
+}
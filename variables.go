@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
+)
+
+const variablesFile = "variables.yaml"
+
+// Line and message of a text/template parse or execution error
+var templateErrorPattern = regexp.MustCompile(`^template: \w+:(\d+)(?::\d+)?: (.*)$`)
+
+// Load the site-wide variables from variables.yaml at the repository root, if it exists
+func loadVariables(root string) (map[string]any, error) {
+	variables := map[string]any{}
+	data, err := os.ReadFile(filepath.Join(root, variablesFile))
+	if os.IsNotExist(err) {
+		return variables, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %v: %v", variablesFile, err)
+	}
+	if err := yaml.Unmarshal(data, &variables); err != nil {
+		return nil, fmt.Errorf("Error parsing %v: %v", variablesFile, err)
+	}
+	return variables, nil
+}
+
+// Merge override into base, combining nested maps so an article can override single values
+func mergeVariables(base map[string]any, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range override {
+		baseMap, baseIsMap := merged[name].(map[string]any)
+		overrideMap, overrideIsMap := value.(map[string]any)
+		if baseIsMap && overrideIsMap {
+			merged[name] = mergeVariables(baseMap, overrideMap)
+		} else {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// A single {{ }} template action
+var templateActionPattern = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// Replace the inline code spans of each line with placeholders, returning the spans in order
+func maskInlineCode(text string) (string, []string) {
+	var spans []string
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = inlineCodePattern.ReplaceAllStringFunc(line, func(code string) string {
+			spans = append(spans, code)
+			return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+		})
+	}
+	return strings.Join(lines, "\n"), spans
+}
+
+var inlineCodePlaceholderPattern = regexp.MustCompile("\x00(\\d+)\x00")
+
+func unmaskInlineCode(text string, spans []string) string {
+	return inlineCodePlaceholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		index, _ := strconv.Atoi(strings.Trim(placeholder, "\x00"))
+		return spans[index]
+	})
+}
+
+// Substitute {{ .site.url }} style variables into the content outside of fenced code blocks and
+// inline code. Undefined variables are errors in strict mode, otherwise they are left as they
+// are with a warning and the rest of the text is still substituted. Returns the substituted content
+// with the line of the content each of its lines came from.
+func substituteVariables(content string, variables map[string]any, strict bool) (string, []int, []Diagnostic) {
+	var diagnostics []Diagnostic
+	severity := severityWarning
+	if strict {
+		severity = severityError
+	}
+	lines, inCode := markdownLines(content)
+	substituted := &lineTracker{}
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for end < len(lines) && inCode[end] == inCode[start] {
+			end++
+		}
+		segment := strings.Join(lines[start:end], "\n")
+		masked, spans := maskInlineCode(segment)
+		// Line of the segment, counting from 0, that each of its substituted lines came from
+		origins := make([]int, end-start)
+		for i := range origins {
+			origins[i] = i
+		}
+		if !inCode[start] && strings.Contains(masked, "{{") {
+			rendered, renderedOrigins, err := renderVariableLines(masked, variables)
+			if err == nil {
+				segment, origins = unmaskInlineCode(rendered, spans), renderedOrigins
+			} else {
+				// Substitute each variable on its own, leaving the ones that fail in the text
+				var failed []Diagnostic
+				maskedLines := strings.Split(masked, "\n")
+				var lineOrigins []int
+				for i, line := range maskedLines {
+					maskedLines[i] = templateActionPattern.ReplaceAllStringFunc(line, func(action string) string {
+						value, err := renderVariables(action, variables)
+						if err != nil {
+							_, message := templateError(err)
+							failed = append(failed, Diagnostic{Severity: severity, Line: start + i + 1, Message: "Template variables: " + message})
+							return action
+						}
+						return value
+					})
+					for range strings.Count(maskedLines[i], "\n") + 1 {
+						lineOrigins = append(lineOrigins, i)
+					}
+				}
+				if len(failed) == 0 {
+					// The segment does not parse as a whole, such as an unclosed action
+					line, message := templateError(err)
+					failed = append(failed, Diagnostic{Severity: severity, Line: start + line, Message: "Template variables: " + message})
+				} else {
+					segment, origins = unmaskInlineCode(strings.Join(maskedLines, "\n"), spans), lineOrigins
+				}
+				diagnostics = append(diagnostics, failed...)
+			}
+		}
+		for i, line := range strings.Split(segment, "\n") {
+			substituted.writeLine(line, start+origins[i])
+		}
+		start = end
+	}
+	return substituted.String(), substituted.origins, diagnostics
+}
+
+// Line, counting from 1, and message of a template error
+func templateError(err error) (int, string) {
+	if match := templateErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return line, match[2]
+	}
+	return 1, err.Error()
+}
+
+func renderVariables(text string, variables map[string]any) (string, error) {
+	parsed, err := template.New("content").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	if err := parsed.Execute(&rendered, variables); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// Render text as a template, returning the line of text, counting from 0, that each rendered line
+// came from. Text between actions keeps its lines, and the output of an action, such as an if
+// block, comes from the line it starts on.
+func renderVariableLines(text string, variables map[string]any) (string, []int, error) {
+	parsed, err := template.New("content").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", nil, err
+	}
+	rendered := &lineTracker{source: text}
+	previous := ""
+	for i, node := range parsed.Tree.Root.Nodes {
+		// The output of each node is found by rendering the nodes up to it, which keeps the
+		// variables declared before it in scope
+		tree := parsed.Tree.Copy()
+		tree.Root.Nodes = tree.Root.Nodes[:i+1]
+		prefix, err := parsed.Clone()
+		if err == nil {
+			prefix, err = prefix.AddParseTree("content", tree)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		var output strings.Builder
+		if err := prefix.Execute(&output, variables); err != nil {
+			return "", nil, err
+		}
+		line := strings.Count(text[:node.Position()], "\n")
+		if _, ok := node.(*parse.TextNode); ok {
+			rendered.writeLines(output.String()[len(previous):], line)
+		} else {
+			rendered.write(output.String()[len(previous):], line)
+		}
+		previous = output.String()
+	}
+	return rendered.String(), rendered.origins, nil
+}
+
+// Substitute the site-wide and front matter variables into an article's content.
+// Set STRICT_VARIABLES=true to fail on undefined variables.
+func substituteArticleVariables(content string, articleFile string, frontMatter FrontMatter) (string, []int, []Diagnostic, error) {
+	variables, err := loadVariables(repoRoot(filepath.Dir(articleFile)))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	variables = mergeVariables(variables, frontMatter.Variables)
+	content, origins, diagnostics := substituteVariables(content, variables, os.Getenv("STRICT_VARIABLES") == "true")
+	return content, origins, diagnostics, nil
+}
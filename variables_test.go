@@ -0,0 +1,86 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeVariables(t *testing.T) {
+	base := map[string]any{"site": map[string]any{"url": "https://example.com", "name": "Example"}, "version": "1.0"}
+	override := map[string]any{"site": map[string]any{"name": "Docs"}, "version": "2.0"}
+	want := map[string]any{"site": map[string]any{"url": "https://example.com", "name": "Docs"}, "version": "2.0"}
+	if got := mergeVariables(base, override); !reflect.DeepEqual(got, want) {
+		t.Fatalf(`mergeVariables = %v, but need %v`, got, want)
+	}
+}
+
+func TestSubstituteVariables(t *testing.T) {
+	variables := map[string]any{"site": map[string]any{"url": "https://example.com"}, "support": map[string]any{"email": "help@example.com"}}
+	content := strings.Join([]string{
+		"Visit {{ .site.url }} or mail {{ .support.email }}.",
+		"```go",
+		`fmt.Println("{{ .Name }}")`,
+		"```",
+		"Missing {{ .product.version }} here",
+	}, "\n")
+
+	got, _, diagnostics := substituteVariables(content, variables, false)
+	want := strings.Join([]string{
+		"Visit https://example.com or mail help@example.com.",
+		"```go",
+		`fmt.Println("{{ .Name }}")`,
+		"```",
+		"Missing {{ .product.version }} here",
+	}, "\n")
+	if got != want {
+		t.Fatalf("substituteVariables =\n%v\nbut need\n%v", got, want)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Severity != severityWarning || diagnostics[0].Line != 5 {
+		t.Fatalf(`substituteVariables diagnostics = %v, but need a warning on line 5`, diagnostics)
+	}
+
+	_, _, diagnostics = substituteVariables(content, variables, true)
+	if len(diagnostics) != 1 || diagnostics[0].Severity != severityError || !strings.Contains(diagnostics[0].Message, "product") {
+		t.Fatalf(`substituteVariables diagnostics = %v, but need an error for the undefined variable in strict mode`, diagnostics)
+	}
+
+	// Undefined variables do not stop the others in their paragraph, and inline code is left alone
+	got, _, diagnostics = substituteVariables("Visit {{ .site.url }} for {{ .product.version }}.\nWrite `{{ .Name }}` in templates.\nMail {{ .support.email }}", variables, false)
+	want = "Visit https://example.com for {{ .product.version }}.\nWrite `{{ .Name }}` in templates.\nMail help@example.com"
+	if got != want || len(diagnostics) != 1 || diagnostics[0].Line != 1 {
+		t.Fatalf("substituteVariables =\n%v\n%v\nbut need\n%v\nwith one warning on line 1", got, diagnostics, want)
+	}
+
+	// Lines removed by a block and added by a value are followed back to the template
+	got, origins, _ := substituteVariables("Intro {{ .site.url }}\n{{ if .beta }}\nBeta only\n{{ end }}\nAfter {{ .lines }}\nEnd", map[string]any{"site": variables["site"], "beta": false, "lines": "a\nb"}, false)
+	if want = "Intro https://example.com\n\nAfter a\nb\nEnd"; got != want || !reflect.DeepEqual(origins, []int{0, 1, 4, 4, 5}) {
+		t.Fatalf("substituteVariables =\n%v\n%v\nbut need\n%v\n[0 1 4 4 5]", got, origins, want)
+	}
+
+	_, _, diagnostics = substituteVariables("Line one\nBroken {{ .site.url", variables, true)
+	if len(diagnostics) != 1 || diagnostics[0].Line != 2 {
+		t.Fatalf(`substituteVariables diagnostics = %v, but need a parse error on line 2`, diagnostics)
+	}
+}
+
+func TestCreateArticlePayloadVariables(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", root)
+	writeTestFile(t, filepath.Join(root, variablesFile), "product:\n  name: Widget\n  version: 1.2.0\n")
+	folder := filepath.Join(root, "release")
+	writeTestFile(t, filepath.Join(folder, "release.md"), "---\nvariables:\n  product:\n    version: 2.0.0\n---\n{{ .product.name }} {{ .product.version }} is out.")
+
+	name, articleFile, photos, err := parseArticle(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Content != "Widget 2.0.0 is out." {
+		t.Fatalf(`createArticlePayload content = %q, but need "Widget 2.0.0 is out."`, article.Content)
+	}
+}
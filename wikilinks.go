@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Obsidian style [[Article]], [[Article#Heading|label]] links and ![[photo.png|alt]] embeds
+var wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\]|#]*)(#[^\]|]*)?(?:\|([^\]]*))?\]\]`)
+
+// An IndexedArticle is an article folder in the repository that other articles can link to
+type IndexedArticle struct {
+	Folder string
+	File   string
+	Title  string
+	Slug   string
+	// Language of the article, or of its folder when it does not set one
+	Language string
+	// Id and public url reported by the server once the article is known to be published
+	ID  *int
+	URL string
+}
+
+// Folder holding every article folder. Set ARTICLES_ROOT relative to the repository root,
+// otherwise the folder containing the article folder is used. Only the article folders directly
+// under this one root are indexed, so articles in other folders cannot be linked to.
+func articlesRoot(articleFolder string) string {
+	if root := os.Getenv("ARTICLES_ROOT"); root != "" {
+		return filepath.Join(repoRoot(articleFolder), root)
+	}
+	absFolder, err := filepath.Abs(articleFolder)
+	if err != nil {
+		return filepath.Dir(articleFolder)
+	}
+	return filepath.Dir(absFolder)
+}
+
+// Indexes of the articles roots read during this run, which are built once
+var articleIndexes = map[string][]IndexedArticle{}
+
+// Index of the articles under root, reading the folders the first time root is asked for
+func articleIndex(root string) ([]IndexedArticle, error) {
+	if index, ok := articleIndexes[root]; ok {
+		return index, nil
+	}
+	index, err := buildArticleIndex(root)
+	if err != nil {
+		return nil, err
+	}
+	articleIndexes[root] = index
+	return index, nil
+}
+
+// Index every article folder under root by title and slug
+func buildArticleIndex(root string) ([]IndexedArticle, error) {
+	folders, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading articles folder %v: %v", root, err)
+	}
+	var index []IndexedArticle
+	for _, folder := range folders {
+		if !folder.IsDir() || strings.HasPrefix(folder.Name(), ".") {
+			continue
+		}
+		articleFolder := filepath.Join(root, folder.Name())
+		name, articleFile, _, err := parseArticle(articleFolder)
+		if err != nil {
+			logger.Debug("Skipping folder that is not an article", "folder", articleFolder, "error", err)
+			continue
+		}
+		data, err := os.ReadFile(articleFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading file: %v", err)
+		}
+		frontMatter, _, err := parseFrontMatter(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading front matter of %v: %v", articleFile, err)
+		}
+		title := strings.ReplaceAll(name, "_", " ")
+		if frontMatter.Title != "" {
+			title = frontMatter.Title
+		}
+		slug := frontMatter.Slug
+		if slug == "" {
+			slug = slugify(title)
+		}
+		language := frontMatter.Lang
+		if language == "" {
+			config, err := loadFolderConfig(articleFolder)
+			if err != nil {
+				return nil, err
+			}
+			language = defaultLanguage(config)
+		}
+		index = append(index, IndexedArticle{Folder: articleFolder, File: articleFile, Title: title, Slug: slug, Language: language})
+	}
+	return index, nil
+}
+
+// Find an article by its title, slug or folder name
+func findIndexedArticle(index []IndexedArticle, target string) (IndexedArticle, bool) {
+	target = strings.TrimSpace(target)
+	for _, article := range index {
+		if strings.EqualFold(article.Title, target) || article.Slug == slugify(target) || filepath.Base(article.Folder) == target {
+			return article, true
+		}
+	}
+	return IndexedArticle{}, false
+}
+
+// Public url of an article built from the ARTICLE_URL pattern, e.g. https://example.com/articles/{slug}/
+func articleURL(slug string) (string, error) {
+	pattern := os.Getenv("ARTICLE_URL")
+	if !strings.Contains(pattern, "{slug}") {
+		return "", fmt.Errorf("Set the ARTICLE_URL env variable to a url containing {slug} to link to other articles")
+	}
+	return strings.ReplaceAll(pattern, "{slug}", slug), nil
+}
+
+// List the names (without extension) of the files in the photos folder
+func photoNames(articlePhotos string) map[string]string {
+	names := map[string]string{}
+	if articlePhotos == "" {
+		return names
+	}
+	files, err := os.ReadDir(articlePhotos)
+	if err != nil {
+		return names
+	}
+	for _, file := range files {
+		names[file.Name()] = strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+	}
+	return names
+}
+
+// Convert wiki embeds to image references to photos and wiki links to the linked article's url.
+// Links that cannot be resolved are reported as errors with their line in the content.
+func convertWikiLinks(content string, photos map[string]string, index []IndexedArticle) (string, []ArticleLink, []Diagnostic) {
+	var links []ArticleLink
+	var diagnostics []Diagnostic
+	lines, inCode := markdownLines(content)
+	for i, line := range lines {
+		if inCode[i] || !strings.Contains(line, "[[") {
+			continue
+		}
+		lines[i] = wikiLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+			match := wikiLinkPattern.FindStringSubmatch(link)
+			embed, target, heading, label := match[1] == "!", strings.TrimSpace(match[2]), match[3], match[4]
+			if embed {
+				photo, ok := photos[filepath.Base(target)]
+				if !ok && slices.Contains(slices.Collect(maps.Values(photos)), target) {
+					photo, ok = target, true
+				}
+				if !ok {
+					diagnostics = append(diagnostics, Diagnostic{Severity: severityError, Line: i + 1,
+						Message: fmt.Sprintf("Embedded image %q is not in the photos folder", target)})
+					return link
+				}
+				if label == "" {
+					label = photo
+				}
+				return fmt.Sprintf("![%v](%v)", label, photo)
+			}
+
+			if target == "" && heading != "" {
+				// Links to a heading in the same article
+				if label == "" {
+					label = strings.TrimPrefix(heading, "#")
+				}
+				return fmt.Sprintf("[%v](#%v)", label, slugify(strings.TrimPrefix(heading, "#")))
+			}
+			linked, ok := findIndexedArticle(index, target)
+			if !ok {
+				diagnostics = append(diagnostics, Diagnostic{Severity: severityError, Line: i + 1,
+					Message: fmt.Sprintf("Linked article %q does not match the title or slug of any article", target)})
+				return link
+			}
+			url, err := articleURL(linked.Slug)
+			if err != nil {
+				diagnostics = append(diagnostics, Diagnostic{Severity: severityError, Line: i + 1, Message: err.Error()})
+				return link
+			}
+			links = append(links, ArticleLink{Line: i + 1, Target: link, URL: url, Article: linked})
+			if heading != "" {
+				url += "#" + slugify(strings.TrimPrefix(heading, "#"))
+			}
+			if label == "" {
+				label = target
+			}
+			return fmt.Sprintf("[%v](%v)", label, url)
+		})
+	}
+	return strings.Join(lines, "\n"), links, diagnostics
+}
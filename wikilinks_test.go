@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertWikiLinks(t *testing.T) {
+	t.Setenv("ARTICLE_URL", "https://example.com/articles/{slug}/")
+	index := []IndexedArticle{
+		{Folder: "articles/intro_to_go", Title: "Intro to Go", Slug: "intro-to-go"},
+		{Folder: "articles/testing", Title: "Testing in Go", Slug: "go-testing"},
+	}
+	photos := map[string]string{"gopher.png": "gopher"}
+	content := strings.Join([]string{
+		"See [[Intro to Go]] and [[go-testing|the testing guide]].",
+		"Jump to [[Intro to Go#Getting Started]] or [[#Setup]].",
+		"![[gopher.png]] ![[gopher.png|A gopher]]",
+		"```",
+		"[[Not converted]]",
+		"```",
+		"[[Missing Article]] ![[missing.png]]",
+	}, "\n")
+	want := strings.Join([]string{
+		"See [Intro to Go](https://example.com/articles/intro-to-go/) and [the testing guide](https://example.com/articles/go-testing/).",
+		"Jump to [Intro to Go](https://example.com/articles/intro-to-go/#getting-started) or [Setup](#setup).",
+		"![gopher](gopher) ![A gopher](gopher)",
+		"```",
+		"[[Not converted]]",
+		"```",
+		"[[Missing Article]] ![[missing.png]]",
+	}, "\n")
+
+	got, links, diagnostics := convertWikiLinks(content, photos, index)
+	if got != want {
+		t.Fatalf("convertWikiLinks =\n%v\nbut need\n%v", got, want)
+	}
+	if len(links) != 3 || links[0].Article.Slug != "intro-to-go" || links[1].Article.Slug != "go-testing" || links[2].Line != 2 {
+		t.Fatalf(`convertWikiLinks links = %+v, but need the three links to other articles`, links)
+	}
+	wantDiagnostics := []Diagnostic{
+		{Severity: severityError, Line: 7, Message: `Linked article "Missing Article" does not match the title or slug of any article`},
+		{Severity: severityError, Line: 7, Message: `Embedded image "missing.png" is not in the photos folder`},
+	}
+	if !reflect.DeepEqual(diagnostics, wantDiagnostics) {
+		t.Fatalf(`convertWikiLinks diagnostics = %v, but need %v`, diagnostics, wantDiagnostics)
+	}
+}
+
+func TestCreateArticlePayloadWikiLinks(t *testing.T) {
+	t.Setenv("ARTICLE_URL", "https://example.com/{slug}")
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "intro_to_go", "intro_to_go.md"), "Intro")
+	writeTestFile(t, filepath.Join(root, "part_two", "index.md"), "---\ntitle: Part Two\nslug: part-2\n---\nSee [[Intro to Go]] and [[Part One]]")
+
+	name, articleFile, photos, err := parseArticle(filepath.Join(root, "part_two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	article, err := createArticlePayload(name, articleFile, photos)
+	if err != nil {
+		t.Fatalf(`createArticlePayload returned unexpected error: %v`, err)
+	}
+	if article.Content != "See [Intro to Go](https://example.com/intro-to-go) and [[Part One]]" {
+		t.Fatalf(`createArticlePayload content = %q`, article.Content)
+	}
+	if !hasErrors(article.Diagnostics) || article.Diagnostics[0].Line != 5 || article.Diagnostics[0].File != articleFile {
+		t.Fatalf(`createArticlePayload diagnostics = %v, but need an error on line 5`, article.Diagnostics)
+	}
+}
+
+func TestArticleIndexIsBuiltOnce(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "first", "first.md"), "First")
+	index, err := articleIndex(root)
+	if err != nil || len(index) != 1 {
+		t.Fatalf(`articleIndex = %v, %v, but need the first article`, index, err)
+	}
+	writeTestFile(t, filepath.Join(root, "second", "second.md"), "Second")
+	if index, _ := articleIndex(root); len(index) != 1 {
+		t.Fatalf(`articleIndex = %v, but need the index built the first time`, index)
+	}
+}